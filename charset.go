@@ -2,7 +2,6 @@ package negotiator
 
 import (
 	"sort"
-	"strconv"
 	"strings"
 )
 
@@ -10,92 +9,217 @@ import (
 type Charset struct {
 	Name    string
 	Quality float64
+	// Range is the charset from the Accept-Charset header that matched.
+	Range string
+	// Index is the position of Range in the Accept-Charset header, or -1 when
+	// the header is missing.
+	Index int
+}
+
+// WithServerCharsetOrder breaks ties between charsets of equal quality by the
+// order of the server's offers instead of the Accept-Charset header.
+func WithServerCharsetOrder() Option {
+	return func(n *Negotiator) {
+		n.charsetOrder = true
+	}
 }
 
 // ParseCharsets parses the Accept-Charset header and returns a list of charsets
-// accepted by the client, sorted by priority.
+// accepted by the client, sorted by priority. Charsets are compared and
+// returned by their canonical names, see CanonicalCharset.
 // See https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Accept-Charset
 func (n *Negotiator) ParseCharsets(available ...string) []string {
 	acceptCharset := n.req.Header.Get("Accept-Charset")
 	if acceptCharset == "" || acceptCharset == "*" {
-		if len(available) == 0 {
-			return []string{}
+		result := make([]string, len(available))
+		for i, charset := range available {
+			result[i] = CanonicalCharset(charset)
 		}
-		return available
+		return result
 	}
 
-	parsedCharsets := splitCharsets(acceptCharset)
-	uniques := uniqueCharsets(parsedCharsets)
-
-	if len(available) > 0 {
-		var filteredCharsets []Charset
-		for _, charset := range uniques {
-			for _, availCharset := range available {
-				if strings.EqualFold(charset.Name, availCharset) {
-					filteredCharsets = append(filteredCharsets, charset)
-					break
-				}
-			}
-		}
-		uniques = filteredCharsets
+	matches := n.CharsetMatches(available...)
+
+	result := make([]string, len(matches))
+	for i, charset := range matches {
+		result[i] = CanonicalCharset(charset.Name)
+	}
+
+	return result
+}
+
+// CharsetMatches returns the available charsets that are acceptable to the
+// client, sorted by priority. Each result carries the charset from the
+// Accept-Charset header that matched it, its position in the header and its
+// quality. Aliases such as utf8 and UTF-8 match each other. Without available
+// charsets, the charsets accepted by the client are returned instead, under
+// their canonical names.
+func (n *Negotiator) CharsetMatches(available ...string) []Charset {
+	return n.NegotiateCharsets(Offers(available...)...)
+}
+
+// NegotiateCharsets is like CharsetMatches, but weighs each offer by its
+// source quality.
+//
+// Charsets are sorted by quality, then charsets named in the header before
+// those only matched by "*", then by their position in the header, then by the
+// order of the offers. A "*" in the header matches every offer not named
+// elsewhere in the header, so "*;q=0" refuses them.
+func (n *Negotiator) NegotiateCharsets(offers ...Offer) []Charset {
+	acceptCharset := n.req.Header.Get("Accept-Charset")
+
+	var preferredCharsets []Charset
+	if acceptCharset == "" || acceptCharset == "*" {
+		preferredCharsets = anyCharsets(acceptCharset, offers)
+	} else if parsedCharsets := splitCharsets(acceptCharset); len(offers) > 0 {
+		preferredCharsets = findPreferredCharsets(parsedCharsets, offers)
+	} else {
+		preferredCharsets = uniqueCharsets(parsedCharsets)
 	}
 
-	sort.SliceStable(uniques, func(i, j int) bool {
-		return uniques[i].Quality > uniques[j].Quality
+	// Charsets come in the server's order, so the stable sort falls back to it
+	// after the quality and the position in the header.
+	sort.SliceStable(preferredCharsets, func(i, j int) bool {
+		if preferredCharsets[i].Quality != preferredCharsets[j].Quality {
+			return preferredCharsets[i].Quality > preferredCharsets[j].Quality
+		}
+		if n.charsetOrder {
+			return false
+		}
+		if wildcardI, wildcardJ := isWildcardCharset(preferredCharsets[i]), isWildcardCharset(preferredCharsets[j]); wildcardI != wildcardJ {
+			return wildcardJ
+		}
+		return preferredCharsets[i].Index < preferredCharsets[j].Index
 	})
 
-	result := make([]string, 0, len(uniques))
-	for _, charset := range uniques {
+	result := make([]Charset, 0, len(preferredCharsets))
+	for _, charset := range preferredCharsets {
 		if charset.Quality > 0 {
-			result = append(result, charset.Name)
+			result = append(result, charset)
 		}
 	}
 
 	return result
 }
 
-// splitCharsets splits the Accept-Charset header into individual charsets with quality values.
-func splitCharsets(input string) []Charset {
-	rawCharsets := strings.Split(input, ",")
-	charsets := make([]Charset, 0, len(rawCharsets))
+// AcceptedCharsets is like NegotiateCharsets, but with WithStrictParsing it
+// returns a *ParseError when the Accept-Charset header is malformed.
+func (n *Negotiator) AcceptedCharsets(offers ...Offer) ([]Charset, error) {
+	if err := n.checkStrict("Accept-Charset"); err != nil {
+		return nil, err
+	}
+
+	return n.NegotiateCharsets(offers...), nil
+}
+
+// anyCharsets returns every offered charset in the server's order, for a
+// client that accepts any charset.
+func anyCharsets(acceptCharset string, offers []Offer) []Charset {
+	index := 0
+	if acceptCharset == "" {
+		index = -1
+	}
+
+	charsets := make([]Charset, len(offers))
+	for i, offer := range offers {
+		charsets[i] = Charset{Name: offer.Value, Quality: offer.sourceQuality(), Range: acceptCharset, Index: index}
+	}
 
-	for _, rawCharset := range rawCharsets {
-		parts := strings.Split(strings.TrimSpace(rawCharset), ";")
-		charset := Charset{
-			Name:    strings.TrimSpace(parts[0]),
-			Quality: 1,
+	return charsets
+}
+
+// findPreferredCharsets returns the offered charsets, each with the quality of
+// the best charset in the header that names it multiplied by the offer's
+// source quality. An offer named nowhere in the header takes the quality of
+// "*". A charset refused with a q-value of 0 keeps a quality of 0.
+func findPreferredCharsets(parsedCharsets []Charset, offers []Offer) []Charset {
+	preferredCharsets := make([]Charset, 0, len(offers))
+	for _, offer := range offers {
+		match := bestCharset(parsedCharsets, offer.Value, func(name string) bool {
+			return charsetKey(name) == charsetKey(offer.Value)
+		})
+		if match.Quality < 0 {
+			match = bestCharset(parsedCharsets, offer.Value, func(name string) bool {
+				return name == "*"
+			})
 		}
 
-		for _, part := range parts[1:] {
-			if strings.HasPrefix(strings.TrimSpace(part), "q=") {
-				if quality, err := strconv.ParseFloat(strings.TrimSpace(part[2:]), 64); err == nil {
-					charset.Quality = quality
-				}
-			}
+		if match.Quality >= 0 {
+			match.Quality *= offer.sourceQuality()
+			preferredCharsets = append(preferredCharsets, match)
+		}
+	}
+
+	return preferredCharsets
+}
+
+// bestCharset returns the offered charset with the highest quality among the
+// charsets in the header that match it, or a quality of -1 when none does. A
+// refusal with a q-value of 0 wins over every other match.
+func bestCharset(parsedCharsets []Charset, offer string, matches func(name string) bool) Charset {
+	match := Charset{Name: offer, Quality: -1}
+	for _, charset := range parsedCharsets {
+		if !matches(charset.Name) {
+			continue
+		}
+		if charset.Quality <= 0 {
+			return Charset{Name: offer, Quality: 0, Range: charset.Name, Index: charset.Index}
+		}
+		if charset.Quality > match.Quality {
+			match.Quality = charset.Quality
+			match.Range = charset.Name
+			match.Index = charset.Index
 		}
+	}
+
+	return match
+}
 
-		charsets = append(charsets, charset)
+// isWildcardCharset checks if an offered charset was only matched by "*".
+func isWildcardCharset(charset Charset) bool {
+	return charset.Range == "*" && charset.Name != "*"
+}
+
+// splitCharsets splits the Accept-Charset header into individual charsets with quality values.
+func splitCharsets(input string) []Charset {
+	rawCharsets := strings.Split(input, ",")
+	charsets := make([]Charset, 0, len(rawCharsets))
+
+	for i, rawCharset := range rawCharsets {
+		name, quality, _ := splitQuality(rawCharset)
+		charsets = append(charsets, Charset{Name: name, Quality: quality, Range: name, Index: i})
 	}
 
 	return charsets
 }
 
 // uniqueCharsets filters the given list of charsets to remove duplicates,
-// retaining the highest quality value for each charset name. it then returns
-// a new slice of Charset with unique charset names.
+// retaining the highest quality value for each canonical charset name. A
+// charset refused with a q-value of 0 stays refused, whatever other entries
+// say about it. The charsets keep the position of their first entry in the
+// header.
 func uniqueCharsets(charsets []Charset) []Charset {
-	charsetMap := map[string]Charset{}
+	unique := make([]Charset, 0, len(charsets))
+	positions := map[string]int{}
 	for _, charset := range charsets {
-		lowerName := strings.ToLower(charset.Name)
-		existing, exists := charsetMap[lowerName]
-		if !exists || existing.Quality < charset.Quality {
-			charsetMap[lowerName] = charset
+		charset.Name = CanonicalCharset(charset.Name)
+		key := strings.ToLower(charset.Name)
+
+		i, exists := positions[key]
+		if !exists {
+			positions[key] = len(unique)
+			unique = append(unique, charset)
+			continue
 		}
-	}
 
-	unique := make([]Charset, 0, len(charsetMap))
-	for _, charset := range charsetMap {
-		unique = append(unique, charset)
+		existing := unique[i]
+		if existing.Quality <= 0 {
+			continue
+		}
+		if existing.Quality < charset.Quality || charset.Quality <= 0 {
+			charset.Index = existing.Index
+			unique[i] = charset
+		}
 	}
 
 	return unique
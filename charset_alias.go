@@ -0,0 +1,110 @@
+package negotiator
+
+import "strings"
+
+// CanonicalCharset returns the canonical name of a charset label, such as
+// UTF-8 for utf8 or unicode-1-1-utf-8 and ISO-8859-1 for latin1. Labels are
+// matched ignoring case and surrounding whitespace. Unknown labels, including
+// the "*" wildcard, are returned trimmed but otherwise unchanged.
+func CanonicalCharset(label string) string {
+	label = strings.TrimSpace(label)
+	if name, ok := charsetLabels[strings.ToLower(label)]; ok {
+		return name
+	}
+
+	return label
+}
+
+// charsetKey returns the key under which two charset labels are equal.
+func charsetKey(label string) string {
+	return strings.ToLower(CanonicalCharset(label))
+}
+
+// The table below combines the IANA Character Sets registry with the label
+// table of the WHATWG Encoding Standard. Where the two disagree, such as
+// WHATWG decoding ISO-8859-1 and US-ASCII as windows-1252, the IANA charset is
+// kept, since a client naming a charset means that charset. For the same
+// reason the WHATWG labels ucs-2 and unicode are left out, as they name
+// UTF-16LE there but the 2-byte UCS-2 elsewhere.
+// See https://www.iana.org/assignments/character-sets and
+// https://encoding.spec.whatwg.org/#names-and-labels
+
+// charsetAliases maps canonical charset names, the IANA preferred MIME names
+// where there is one, to their lower-case aliases and labels.
+var charsetAliases = map[string][]string{
+	"UTF-8":           {"utf8", "csutf8", "unicode-1-1-utf-8", "unicode11utf8", "unicode20utf8", "x-unicode20utf8"},
+	"UTF-16":          {"csutf16"},
+	"UTF-16BE":        {"csutf16be", "unicodefffe"},
+	"UTF-16LE":        {"csutf16le", "unicodefeff"},
+	"UTF-32":          {"csutf32"},
+	"UTF-32BE":        {"csutf32be"},
+	"UTF-32LE":        {"csutf32le"},
+	"ISO-10646-UCS-2": {"csunicode"},
+	"US-ASCII":        {"ascii", "us", "iso-ir-6", "ansi_x3.4-1968", "ansi_x3.4-1986", "iso_646.irv:1991", "iso646-us", "ibm367", "cp367", "csascii"},
+	"ISO-8859-1":      {"iso_8859-1", "iso_8859-1:1987", "iso8859-1", "iso88591", "iso-ir-100", "latin1", "l1", "ibm819", "cp819", "csisolatin1"},
+	"ISO-8859-2":      {"iso_8859-2", "iso_8859-2:1987", "iso8859-2", "iso88592", "iso-ir-101", "latin2", "l2", "csisolatin2"},
+	"ISO-8859-3":      {"iso_8859-3", "iso_8859-3:1988", "iso8859-3", "iso88593", "iso-ir-109", "latin3", "l3", "csisolatin3"},
+	"ISO-8859-4":      {"iso_8859-4", "iso_8859-4:1988", "iso8859-4", "iso88594", "iso-ir-110", "latin4", "l4", "csisolatin4"},
+	"ISO-8859-5":      {"iso_8859-5", "iso_8859-5:1988", "iso8859-5", "iso88595", "iso-ir-144", "cyrillic", "csisolatincyrillic"},
+	"ISO-8859-6":      {"iso_8859-6", "iso_8859-6:1987", "iso8859-6", "iso88596", "iso-ir-127", "ecma-114", "asmo-708", "arabic", "csisolatinarabic"},
+	"ISO-8859-6-E":    {"iso_8859-6-e", "csiso88596e"},
+	"ISO-8859-6-I":    {"iso_8859-6-i", "csiso88596i"},
+	"ISO-8859-7":      {"iso_8859-7", "iso_8859-7:1987", "iso8859-7", "iso88597", "iso-ir-126", "elot_928", "ecma-118", "greek", "greek8", "sun_eu_greek", "csisolatingreek"},
+	"ISO-8859-8":      {"iso_8859-8", "iso_8859-8:1988", "iso8859-8", "iso88598", "iso-ir-138", "hebrew", "visual", "csisolatinhebrew"},
+	"ISO-8859-8-E":    {"iso_8859-8-e", "csiso88598e"},
+	"ISO-8859-8-I":    {"iso_8859-8-i", "csiso88598i", "logical"},
+	"ISO-8859-9":      {"iso_8859-9", "iso_8859-9:1989", "iso8859-9", "iso88599", "iso-ir-148", "latin5", "l5", "csisolatin5"},
+	"ISO-8859-10":     {"iso_8859-10:1992", "iso8859-10", "iso885910", "iso-ir-157", "latin6", "l6", "csisolatin6"},
+	"ISO-8859-11":     {"iso8859-11", "iso885911"},
+	"ISO-8859-13":     {"iso8859-13", "iso885913", "csiso885913"},
+	"ISO-8859-14":     {"iso_8859-14", "iso_8859-14:1998", "iso8859-14", "iso885914", "iso-ir-199", "iso-celtic", "latin8", "l8", "csiso885914"},
+	"ISO-8859-15":     {"iso_8859-15", "iso8859-15", "iso885915", "latin-9", "l9", "csiso885915", "csisolatin9"},
+	"ISO-8859-16":     {"iso_8859-16", "iso_8859-16:2001", "iso-ir-226", "latin10", "l10", "csiso885916"},
+	"TIS-620":         {"cstis620"},
+	"KOI8-R":          {"koi", "koi8", "koi8_r", "cskoi8r"},
+	"KOI8-U":          {"koi8-ru", "cskoi8u"},
+	"IBM866":          {"866", "cp866", "csibm866"},
+	"macintosh":       {"mac", "x-mac-roman", "csmacintosh"},
+	"x-mac-cyrillic":  {"x-mac-ukrainian"},
+	"windows-874":     {"dos-874", "cswindows874"},
+	"windows-1250":    {"cp1250", "x-cp1250", "cswindows1250"},
+	"windows-1251":    {"cp1251", "x-cp1251", "cswindows1251"},
+	"windows-1252":    {"cp1252", "x-cp1252", "cswindows1252"},
+	"windows-1253":    {"cp1253", "x-cp1253", "cswindows1253"},
+	"windows-1254":    {"cp1254", "x-cp1254", "cswindows1254"},
+	"windows-1255":    {"cp1255", "x-cp1255", "cswindows1255"},
+	"windows-1256":    {"cp1256", "x-cp1256", "cswindows1256"},
+	"windows-1257":    {"cp1257", "x-cp1257", "cswindows1257"},
+	"windows-1258":    {"cp1258", "x-cp1258", "cswindows1258"},
+	"GBK":             {"cp936", "ms936", "windows-936", "x-gbk", "csgbk"},
+	"GB2312":          {"csgb2312"},
+	"GB_2312-80":      {"gb_2312", "iso-ir-58", "chinese", "csiso58gb231280"},
+	"GB18030":         {"csgb18030"},
+	"HZ-GB-2312":      {},
+	"Big5":            {"big-5", "cn-big5", "x-x-big5", "csbig5"},
+	"Big5-HKSCS":      {"csbig5hkscs"},
+	"EUC-JP":          {"extended_unix_code_packed_format_for_japanese", "x-euc-jp", "cseucpkdfmtjapanese"},
+	"ISO-2022-JP":     {"csiso2022jp"},
+	"Shift_JIS":       {"ms_kanji", "sjis", "x-sjis", "ms932", "csshiftjis"},
+	"Windows-31J":     {"cswindows31j"},
+	"EUC-KR":          {"windows-949", "cseuckr"},
+	"KS_C_5601-1987":  {"ks_c_5601-1989", "ksc5601", "ksc_5601", "iso-ir-149", "korean", "csksc56011987"},
+	"ISO-2022-KR":     {"csiso2022kr"},
+	"ISO-2022-CN":     {"csiso2022cn"},
+	"ISO-2022-CN-EXT": {"csiso2022cnext"},
+	"x-user-defined":  {},
+}
+
+// charsetLabels maps every lower-case charset name and label to its canonical
+// name.
+var charsetLabels = func() map[string]string {
+	labels := make(map[string]string)
+	for name, aliases := range charsetAliases {
+		labels[strings.ToLower(name)] = name
+		for _, alias := range aliases {
+			labels[alias] = name
+		}
+	}
+
+	return labels
+}()
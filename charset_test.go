@@ -134,6 +134,18 @@ func TestNegotiator_ParseCharsets(t *testing.T) {
 			[]string{"UTF-8", "ISO-8859-1"},
 			[]string{"ISO-8859-1", "UTF-8"},
 		},
+		{
+			"should keep a refused charset refused",
+			"UTF-8;q=0, ISO-8859-1, UTF-8",
+			[]string{"ISO-8859-1"},
+			[]string{"UTF-8", "ISO-8859-1"},
+		},
+		{
+			"should exclude a charset refused with spaces around the parameter",
+			"utf-8; q=0, *",
+			[]string{"ISO-8859-1"},
+			[]string{"UTF-8", "ISO-8859-1"},
+		},
 		{
 			"should return original list",
 			"",
@@ -177,3 +189,198 @@ func TestNegotiator_ParseCharsets(t *testing.T) {
 	}
 
 }
+
+func TestNegotiator_CharsetMatches(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Charset", "ISO-8859-1;q=0.5, UTF-8")
+
+	matches := negotiator.New(req).CharsetMatches("iso-8859-1", "utf-8", "KOI8-R")
+
+	expected := []negotiator.Charset{
+		{Name: "utf-8", Quality: 1, Range: "UTF-8", Index: 1},
+		{Name: "iso-8859-1", Quality: 0.5, Range: "ISO-8859-1", Index: 0},
+	}
+	if len(matches) != len(expected) {
+		t.Fatalf("Expected %v charsets, got %v", expected, matches)
+	}
+	for i, v := range matches {
+		if v != expected[i] {
+			t.Errorf("Expected %v charset, got %v", expected[i], v)
+		}
+	}
+}
+
+func TestNegotiator_CharsetAliases(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		acceptCharset string
+		expected      []string
+		available     []string
+	}{
+		{
+			"should match WHATWG labels",
+			"utf8, unicode-1-1-utf-8;q=0.5",
+			[]string{"UTF-8"},
+			[]string{"utf-8"},
+		},
+		{
+			"should match IANA aliases",
+			"latin1, koi8;q=0.5",
+			[]string{"ISO-8859-1", "KOI8-R"},
+			[]string{"cskoi8r", "iso_8859-1:1987"},
+		},
+		{
+			"should exclude aliases of a refused charset",
+			"latin1, utf8;q=0, UTF-8",
+			[]string{"ISO-8859-1"},
+			[]string{"UTF-8", "l1"},
+		},
+		{
+			"should keep ISO-8859-1 apart from windows-1252",
+			"windows-1252",
+			[]string{},
+			[]string{"iso-8859-1"},
+		},
+		{
+			"should merge aliases in the client's list",
+			"utf8;q=0.5, UTF-8, latin1;q=0.8",
+			[]string{"UTF-8", "ISO-8859-1"},
+			nil,
+		},
+		{
+			"should return canonical names without a header",
+			"",
+			[]string{"UTF-8", "ISO-8859-1"},
+			[]string{"utf8", "latin1"},
+		},
+		{
+			"should return canonical names for a wildcard",
+			"*",
+			[]string{"UTF-8", "ISO-8859-1"},
+			[]string{"utf8", "latin1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Accept-Charset", test.acceptCharset)
+
+			actual := negotiator.New(req).ParseCharsets(test.available...)
+			if len(actual) != len(test.expected) {
+				t.Fatalf("Expected %v charsets, got %v", test.expected, actual)
+			}
+			for i, v := range actual {
+				if v != test.expected[i] {
+					t.Errorf("Expected %s charset, got %s", test.expected[i], v)
+				}
+			}
+		})
+	}
+}
+
+func TestCanonicalCharset(t *testing.T) {
+	tests := map[string]string{
+		"utf8":               "UTF-8",
+		" Unicode-1-1-UTF-8": "UTF-8",
+		"latin1":             "ISO-8859-1",
+		"ANSI_X3.4-1968":     "US-ASCII",
+		"x-sjis":             "Shift_JIS",
+		"cp1252":             "windows-1252",
+		"x-unknown":          "x-unknown",
+		"unicode":            "unicode",
+		"ucs-2":              "ucs-2",
+		"*":                  "*",
+	}
+
+	for label, expected := range tests {
+		if actual := negotiator.CanonicalCharset(label); actual != expected {
+			t.Errorf("Expected %s for %q, got %s", expected, label, actual)
+		}
+	}
+}
+
+func TestNegotiator_CharsetWildcards(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		acceptCharset string
+		expected      []string
+		available     []string
+	}{
+		{
+			"should rank unnamed charsets by the wildcard",
+			"utf-8, *;q=0.1",
+			[]string{"UTF-8", "ISO-8859-1", "KOI8-R"},
+			[]string{"ISO-8859-1", "UTF-8", "KOI8-R"},
+		},
+		{
+			"should refuse unnamed charsets",
+			"iso-8859-1;q=0.5, *;q=0",
+			[]string{"ISO-8859-1"},
+			[]string{"UTF-8", "ISO-8859-1"},
+		},
+		{
+			"should refuse unnamed charsets with spaces around the parameter",
+			"utf-8, * ; q=0",
+			[]string{"UTF-8"},
+			[]string{"UTF-8", "ISO-8859-1", "KOI8-R"},
+		},
+		{
+			"should prefer named charsets over the wildcard",
+			"*, koi8-r",
+			[]string{"KOI8-R", "UTF-8", "ISO-8859-1"},
+			[]string{"UTF-8", "ISO-8859-1", "KOI8-R"},
+		},
+		{
+			"should order equal charsets by the header",
+			"iso-8859-1, utf-8",
+			[]string{"ISO-8859-1", "UTF-8"},
+			[]string{"UTF-8", "ISO-8859-1"},
+		},
+		{
+			"should order the client's list by the header",
+			"koi8-r;q=0.5, utf-8;q=0.5, iso-8859-1;q=0.5, *;q=0.5",
+			[]string{"KOI8-R", "UTF-8", "ISO-8859-1", "*"},
+			nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Accept-Charset", test.acceptCharset)
+
+			// Run repeatedly, as the order used to depend on map iteration.
+			for run := 0; run < 20; run++ {
+				actual := negotiator.New(req).ParseCharsets(test.available...)
+				if len(actual) != len(test.expected) {
+					t.Fatalf("Expected %v charsets, got %v", test.expected, actual)
+				}
+				for i, v := range actual {
+					if v != test.expected[i] {
+						t.Fatalf("Expected %v charsets, got %v", test.expected, actual)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestNegotiator_WithServerCharsetOrder(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Charset", "iso-8859-1, *, koi8-r;q=0.5")
+
+	actual := negotiator.New(req, negotiator.WithServerCharsetOrder()).ParseCharsets("UTF-8", "KOI8-R", "ISO-8859-1")
+
+	expected := []string{"UTF-8", "ISO-8859-1", "KOI8-R"}
+	if len(actual) != len(expected) {
+		t.Fatalf("Expected %v charsets, got %v", expected, actual)
+	}
+	for i, v := range actual {
+		if v != expected[i] {
+			t.Errorf("Expected %s charset, got %s", expected[i], v)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package negotiator
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Compression configures middleware that compresses responses with the
+// content coding negotiated from the Accept-Encoding header.
+type Compression struct {
+	// Level is the compression level used by encoders without a level of
+	// their own. For gzip and deflate it runs from 1 for the fastest to 9 for
+	// the smallest output. Zero means the default level of each coding.
+	Level int
+	// Encoders holds the content codings to compress with. It defaults to
+	// DefaultEncoders.
+	Encoders *EncoderRegistry
+	// MinSize is the size in bytes below which responses are not worth
+	// compressing. Up to MinSize bytes are buffered before deciding. Zero
+	// compresses responses of any size.
+	MinSize int
+	// Allow lists the media ranges of the responses to compress, such as
+	// text/* or application/*+json. When empty, responses of every media type
+	// not denied are compressed.
+	Allow []string
+	// Deny lists the media ranges of the responses never to compress, such as
+	// image/*. When a response matches both lists, the most specific media
+	// range wins, so image/svg+xml in Allow overrides image/* in Deny.
+	Deny []string
+}
+
+// Compress returns middleware that compresses responses with the default
+// settings of Compression.
+func Compress(next http.Handler) http.Handler {
+	return (&Compression{}).Handler(next)
+}
+
+// Handler returns middleware that compresses responses with the registered
+// content coding the client prefers, breaking ties by the rank of the
+// encoders. It sets the Content-Encoding and Vary headers, removes the
+// Content-Length header and weakens the ETag of compressed responses.
+// Responses that already have a Content-Encoding, responses without content,
+// partial responses, responses smaller than MinSize or whose media type is not
+// allowed, and requests without an Accept-Encoding header or that refuse every
+// coding are left uncompressed.
+func (c *Compression) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		if r.Header.Get("Accept-Encoding") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		encoders := c.encoders()
+		encoding, err := New(r, WithEncoders(encoders)).Encoding()
+		if err != nil || encoding.Name == "identity" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		encoder, ok := encoders.Lookup(encoding.Name)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if encoder.Level == 0 {
+			encoder.Level = c.Level
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoder: encoder, compression: c}
+		defer cw.Close()
+
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// compressWriter is an http.ResponseWriter that buffers the start of the
+// response until it can tell whether the response is worth compressing.
+type compressWriter struct {
+	http.ResponseWriter
+	encoder     Encoder
+	compression *Compression
+	statusCode  int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	compressor  io.WriteCloser
+}
+
+// WriteHeader records the status code, which is written once the response is
+// known to be worth compressing or not.
+func (cw *compressWriter) WriteHeader(statusCode int) {
+	if cw.wroteHeader {
+		return
+	}
+	if statusCode >= 100 && statusCode < 200 {
+		// Informational responses precede the final one.
+		cw.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	cw.wroteHeader = true
+	cw.statusCode = statusCode
+
+	if !cw.eligible() {
+		cw.decide(false)
+	}
+}
+
+// Write buffers p until MinSize bytes are written, detecting the Content-Type
+// from the uncompressed content when it is not set.
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.wroteHeader {
+		if cw.Header().Get("Content-Type") == "" {
+			cw.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.decided {
+		if cw.compressor == nil {
+			return cw.ResponseWriter.Write(p)
+		}
+		return cw.compressor.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= cw.compression.MinSize {
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// eligible checks if the header of the response allows compressing it.
+func (cw *compressWriter) eligible() bool {
+	header := cw.Header()
+	if contentEncoding := header.Get("Content-Encoding"); contentEncoding != "" && !strings.EqualFold(contentEncoding, "identity") {
+		return false
+	}
+	if !bodyAllowed(cw.statusCode) {
+		return false
+	}
+	// The byte ranges of a partial response refer to the uncompressed content.
+	if cw.statusCode == http.StatusPartialContent || header.Get("Content-Range") != "" {
+		return false
+	}
+	if contentLength, err := strconv.Atoi(header.Get("Content-Length")); err == nil && contentLength < cw.compression.MinSize {
+		return false
+	}
+
+	return cw.compression.allows(header.Get("Content-Type"))
+}
+
+// decide writes the header, compressing the response if asked to, and then
+// the buffered content.
+func (cw *compressWriter) decide(compress bool) error {
+	cw.decided = true
+
+	if compress {
+		compressor, err := cw.encoder.NewWriter(cw.ResponseWriter, cw.encoder.Level)
+		if err == nil {
+			cw.compressor = compressor
+			cw.Header().Set("Content-Encoding", cw.encoder.Name)
+			cw.Header().Del("Content-Length")
+			// The compressed content is not byte for byte the content a strong
+			// ETag validates.
+			if etag := cw.Header().Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+				cw.Header().Set("ETag", "W/"+etag)
+			}
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.statusCode)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if cw.compressor == nil {
+		_, err := cw.ResponseWriter.Write(buf)
+		return err
+	}
+
+	_, err := cw.compressor.Write(buf)
+	return err
+}
+
+// Flush writes the data compressed so far to the client. A response flushed
+// before reaching MinSize is compressed, as more content is likely to follow.
+func (cw *compressWriter) Flush() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.decided {
+		cw.decide(true)
+	}
+	if flusher, ok := cw.compressor.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets the handler take over the connection, if the underlying
+// ResponseWriter supports it.
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("negotiator: ResponseWriter does not implement http.Hijacker")
+	}
+
+	return hijacker.Hijack()
+}
+
+// Close writes a response that stayed below MinSize uncompressed, and
+// finishes the compressed stream.
+func (cw *compressWriter) Close() error {
+	if cw.wroteHeader && !cw.decided {
+		if err := cw.decide(false); err != nil {
+			return err
+		}
+	}
+	if cw.compressor == nil {
+		return nil
+	}
+
+	return cw.compressor.Close()
+}
+
+// allows checks if responses of a media type may be compressed. The most
+// specific media range of Allow and Deny that matches decides, and Deny wins
+// ties.
+func (c *Compression) allows(contentType string) bool {
+	if len(c.Allow) == 0 && len(c.Deny) == 0 {
+		return true
+	}
+
+	mediaType := parseMediaType(contentType)
+	if mediaType == nil || mediaType.Type == "" || mediaType.Subtype == "" {
+		return len(c.Allow) == 0
+	}
+
+	allowed, denied := mediaRangesMatch(c.Allow, *mediaType), mediaRangesMatch(c.Deny, *mediaType)
+	if len(c.Allow) == 0 {
+		return denied < 0
+	}
+
+	return allowed >= 0 && allowed > denied
+}
+
+// mediaRangesMatch returns the specificity of the most specific media range
+// that matches the media type, or -1 when none does. Structured syntax
+// suffixes match, so application/*+json matches application/ld+json.
+func mediaRangesMatch(mediaRanges []string, mediaType MediaType) int {
+	n := &Negotiator{suffixMatching: true}
+
+	best := -1
+	for _, mediaRange := range mediaRanges {
+		parsed := parseMediaType(mediaRange)
+		if parsed == nil {
+			continue
+		}
+		if specificity := n.matchMediaRange(*parsed, mediaType); specificity > best {
+			best = specificity
+		}
+	}
+
+	return best
+}
+
+// encoders returns the registry of the content codings to compress with.
+func (c *Compression) encoders() *EncoderRegistry {
+	if c.Encoders == nil {
+		return DefaultEncoders
+	}
+
+	return c.Encoders
+}
+
+// bodyAllowed checks if a response with the status code may have content.
+func bodyAllowed(statusCode int) bool {
+	return statusCode != http.StatusNoContent && statusCode != http.StatusNotModified
+}
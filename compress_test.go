@@ -0,0 +1,231 @@
+package negotiator_test
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"github.com/noelukwa/negotiator"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompress(t *testing.T) {
+	body := strings.Repeat("hello, world\n", 100)
+
+	tests := []struct {
+		name            string
+		acceptEncoding  string
+		contentEncoding string
+		expected        string
+	}{
+		{"should compress with gzip", "gzip, deflate", "", "gzip"},
+		{"should compress with deflate", "gzip;q=0.5, deflate", "", "deflate"},
+		{"should not compress without a header", "", "", ""},
+		{"should not compress when refused", "br", "", ""},
+		{"should not compress encoded responses", "gzip", "br", "br"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := negotiator.Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Length", "1300")
+				if test.contentEncoding != "" {
+					w.Header().Set("Content-Encoding", test.contentEncoding)
+				}
+				io.WriteString(w, body)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", test.acceptEncoding)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if contentEncoding := rec.Header().Get("Content-Encoding"); contentEncoding != test.expected {
+				t.Fatalf("Expected Content-Encoding %q, got %q", test.expected, contentEncoding)
+			}
+			if vary := rec.Header().Get("Vary"); vary != "Accept-Encoding" {
+				t.Errorf("Expected Vary Accept-Encoding, got %q", vary)
+			}
+			if contentType := rec.Header().Get("Content-Type"); contentType != "text/plain; charset=utf-8" {
+				t.Errorf("Expected the Content-Type of the uncompressed content, got %q", contentType)
+			}
+
+			var reader io.Reader = rec.Body
+			switch test.expected {
+			case "gzip":
+				reader, _ = gzip.NewReader(rec.Body)
+			case "deflate":
+				reader, _ = zlib.NewReader(rec.Body)
+			}
+			if test.expected == "gzip" || test.expected == "deflate" {
+				if contentLength := rec.Header().Get("Content-Length"); contentLength != "" {
+					t.Errorf("Expected no Content-Length, got %s", contentLength)
+				}
+			}
+
+			actual, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(actual) != body {
+				t.Errorf("Expected the body to round-trip, got %q", actual)
+			}
+		})
+	}
+}
+
+func TestCompress_Flush(t *testing.T) {
+	handler := negotiator.Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "first")
+		w.(http.Flusher).Flush()
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if !rec.Flushed {
+		t.Errorf("Expected the response to be flushed")
+	}
+}
+
+func TestCompress_PartialContent(t *testing.T) {
+	body := strings.Repeat("hello, world\n", 100)
+	handler := negotiator.Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "hello.txt", time.Time{}, strings.NewReader(body))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Range", "bytes=0-11")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("Expected status %d, got %d", http.StatusPartialContent, rec.Code)
+	}
+	if contentEncoding := rec.Header().Get("Content-Encoding"); contentEncoding != "" {
+		t.Errorf("Expected no Content-Encoding, got %s", contentEncoding)
+	}
+	if rec.Body.String() != "hello, world" {
+		t.Errorf("Expected the requested range, got %q", rec.Body.String())
+	}
+}
+
+func TestCompress_ETag(t *testing.T) {
+	tests := map[string]string{
+		`"v1"`:   `W/"v1"`,
+		`W/"v1"`: `W/"v1"`,
+	}
+
+	for etag, expected := range tests {
+		handler := negotiator.Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", etag)
+			io.WriteString(w, "hello")
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if actual := rec.Header().Get("ETag"); actual != expected {
+			t.Errorf("Expected ETag %s for %s, got %s", expected, etag, actual)
+		}
+	}
+}
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (hr *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hr.hijacked = true
+	return nil, nil, nil
+}
+
+func TestCompress_Hijack(t *testing.T) {
+	handler := negotiator.Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			t.Fatalf("Expected the ResponseWriter to implement http.Hijacker")
+		}
+		hijacker.Hijack()
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	handler.ServeHTTP(rec, req)
+
+	if !rec.hijacked {
+		t.Errorf("Expected the connection to be hijacked")
+	}
+}
+
+func TestCompression_Eligibility(t *testing.T) {
+	compression := &negotiator.Compression{
+		MinSize: 256,
+		Allow:   []string{"text/*", "application/*+json", "application/json", "image/svg+xml"},
+		Deny:    []string{"image/*", "text/event-stream"},
+	}
+
+	large := strings.Repeat("x", 1024)
+
+	tests := []struct {
+		name          string
+		contentType   string
+		contentLength string
+		writes        []string
+		expected      string
+	}{
+		{"should compress large allowed responses", "text/html; charset=utf-8", "", []string{large}, "gzip"},
+		{"should compress once the buffer reaches MinSize", "application/json", "", []string{large[:200], large[:200]}, "gzip"},
+		{"should compress suffixed media types", "application/ld+json", "", []string{large}, "gzip"},
+		{"should not compress small responses", "application/json", "", []string{`{"ok":true}`}, ""},
+		{"should not compress a small Content-Length", "text/plain", "11", []string{"hello world"}, ""},
+		{"should not compress denied media types", "image/png", "", []string{large}, ""},
+		{"should prefer the more specific allowed media range", "image/svg+xml", "", []string{large}, "gzip"},
+		{"should prefer the more specific denied media range", "text/event-stream", "", []string{large}, ""},
+		{"should not compress media types not allowed", "application/octet-stream", "", []string{large}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := compression.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", test.contentType)
+				if test.contentLength != "" {
+					w.Header().Set("Content-Length", test.contentLength)
+				}
+				for _, write := range test.writes {
+					io.WriteString(w, write)
+				}
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if contentEncoding := rec.Header().Get("Content-Encoding"); contentEncoding != test.expected {
+				t.Fatalf("Expected Content-Encoding %q, got %q", test.expected, contentEncoding)
+			}
+
+			var reader io.Reader = rec.Body
+			if test.expected == "gzip" {
+				reader, _ = gzip.NewReader(rec.Body)
+			}
+			actual, _ := io.ReadAll(reader)
+			if string(actual) != strings.Join(test.writes, "") {
+				t.Errorf("Expected the body to round-trip, got %q", actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package negotiator
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrMissingContentType is returned when a request has no Content-Type.
+	ErrMissingContentType = errors.New("negotiator: missing Content-Type")
+	// ErrInvalidContentType is returned when the Content-Type of a request
+	// is not a valid media type.
+	ErrInvalidContentType = errors.New("negotiator: invalid Content-Type")
+	// ErrUnsupportedMediaType is returned when the Content-Type of a request
+	// matches none of the accepted media types.
+	ErrUnsupportedMediaType = errors.New("negotiator: unsupported media type")
+)
+
+// ContentType matches the Content-Type of the request against the media types
+// the handler accepts, which may be media ranges such as text/* and may carry
+// parameters such as charset=utf-8. It returns the media type of the request,
+// with Range set to the most specific accepted media type that matched and
+// Index to its position in accepted. When nothing matches, it returns
+// ErrMissingContentType, ErrInvalidContentType or ErrUnsupportedMediaType.
+func (n *Negotiator) ContentType(accepted ...string) (MediaType, error) {
+	contentType := n.req.Header.Get("Content-Type")
+	if strings.TrimSpace(contentType) == "" {
+		return MediaType{}, ErrMissingContentType
+	}
+
+	requestMediaType := parseMediaType(contentType)
+	if requestMediaType == nil || requestMediaType.Type == "*" || requestMediaType.Subtype == "*" {
+		return MediaType{}, ErrInvalidContentType
+	}
+
+	match, best := *requestMediaType, -1
+	for i, accept := range accepted {
+		acceptedMediaType := parseMediaType(accept)
+		if acceptedMediaType == nil {
+			continue
+		}
+
+		if specificity := n.matchMediaRange(*acceptedMediaType, *requestMediaType); specificity > best {
+			match.Range = acceptedMediaType.Value
+			match.Index = i
+			best = specificity
+		}
+	}
+
+	if best < 0 {
+		return *requestMediaType, ErrUnsupportedMediaType
+	}
+
+	return match, nil
+}
+
+// RequireContentType returns middleware that answers requests with a body
+// whose Content-Type matches none of the accepted media types with 415
+// Unsupported Media Type. The response lists the accepted media types in the
+// Accept-Post header for POST requests and in the Accept-Patch header for
+// PATCH requests. Requests without a body are passed through.
+func RequireContentType(accepted ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength == 0 && r.Header.Get("Content-Type") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if _, err := New(r).ContentType(accepted...); err != nil {
+				switch r.Method {
+				case http.MethodPost:
+					w.Header().Set("Accept-Post", strings.Join(accepted, ", "))
+				case http.MethodPatch:
+					w.Header().Set("Accept-Patch", strings.Join(accepted, ", "))
+				}
+				http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package negotiator_test
+
+import (
+	"errors"
+	"github.com/noelukwa/negotiator"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiator_ContentType(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		contentType string
+		accepted    []string
+		expected    string
+		err         error
+	}{
+		{"should match an exact media type", "application/json", []string{"text/plain", "application/json"}, "application/json", nil},
+		{"should match a media range", "text/csv; charset=utf-8", []string{"application/json", "text/*"}, "text/*", nil},
+		{"should match the charset case-insensitively", "text/plain; charset=UTF-8", []string{"text/plain;charset=utf-8"}, "text/plain;charset=utf-8", nil},
+		{"should prefer the most specific accepted media type", "text/plain; charset=utf-8", []string{"*/*", "text/plain;charset=utf-8", "text/plain"}, "text/plain;charset=utf-8", nil},
+		{"should reject a different charset", "text/plain; charset=iso-8859-1", []string{"text/plain;charset=utf-8"}, "", negotiator.ErrUnsupportedMediaType},
+		{"should reject an unsupported media type", "application/xml", []string{"application/json"}, "", negotiator.ErrUnsupportedMediaType},
+		{"should reject a missing Content-Type", "", []string{"application/json"}, "", negotiator.ErrMissingContentType},
+		{"should reject an invalid Content-Type", "json", []string{"application/json"}, "", negotiator.ErrInvalidContentType},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.Header.Set("Content-Type", test.contentType)
+
+			mediaType, err := negotiator.New(req).ContentType(test.accepted...)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("Expected %v error, got %v", test.err, err)
+			}
+			if err == nil && mediaType.Range != test.expected {
+				t.Errorf("Expected %s media type, got %s", test.expected, mediaType.Range)
+			}
+		})
+	}
+}
+
+func TestRequireContentType(t *testing.T) {
+	handler := negotiator.RequireContentType("application/json", "application/merge-patch+json")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		status      int
+		header      string
+	}{
+		{"should pass a supported media type", http.MethodPost, "application/json", "{}", http.StatusNoContent, ""},
+		{"should pass a request without a body", http.MethodPost, "", "", http.StatusNoContent, ""},
+		{"should list accepted media types for POST", http.MethodPost, "text/plain", "hi", http.StatusUnsupportedMediaType, "Accept-Post"},
+		{"should list accepted media types for PATCH", http.MethodPatch, "text/plain", "hi", http.StatusUnsupportedMediaType, "Accept-Patch"},
+		{"should reject a body without a Content-Type", http.MethodPut, "", "hi", http.StatusUnsupportedMediaType, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, "/", strings.NewReader(test.body))
+			if test.contentType != "" {
+				req.Header.Set("Content-Type", test.contentType)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != test.status {
+				t.Errorf("Expected %d status, got %d", test.status, rec.Code)
+			}
+			if test.header != "" && rec.Header().Get(test.header) != "application/json, application/merge-patch+json" {
+				t.Errorf("Expected %s header to list the accepted media types, got %q", test.header, rec.Header().Get(test.header))
+			}
+		})
+	}
+}
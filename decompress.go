@@ -0,0 +1,159 @@
+package negotiator
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Decompress returns middleware that decodes request bodies according to
+// their Content-Encoding header, with the encoders of the registry, or of
+// DefaultEncoders when it is nil. Stacked codings such as "gzip, deflate" are
+// decoded in reverse order. The handler sees the decoded body, without the
+// Content-Encoding and Content-Length headers.
+//
+// Requests in a coding the registry cannot decode are answered with 415
+// Unsupported Media Type, listing the codings it can decode in the
+// Accept-Encoding header, and bodies that fail to decode with 400 Bad Request.
+//
+// The decoded body is limited to maxSize bytes, since a small compressed body
+// can decode to far more. Reading past the limit returns an
+// *http.MaxBytesError, and the request is answered with 413 Request Entity Too
+// Large in place of whatever the handler writes afterwards. A maxSize of zero
+// or less leaves the decoded body unlimited.
+func Decompress(encoders *EncoderRegistry, maxSize int64) func(http.Handler) http.Handler {
+	if encoders == nil {
+		encoders = DefaultEncoders
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			codings := contentCodings(r.Header.Get("Content-Encoding"))
+			if len(codings) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			decoders := make([]Encoder, len(codings))
+			for i, coding := range codings {
+				encoder, ok := encoders.Lookup(coding)
+				if !ok || encoder.NewReader == nil {
+					w.Header().Set("Accept-Encoding", strings.Join(encoders.decoderNames(), ", "))
+					http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+					return
+				}
+				decoders[i] = encoder
+			}
+
+			body := r.Body
+			for i := len(decoders) - 1; i >= 0; i-- {
+				reader, err := decoders[i].NewReader(body)
+				if err != nil {
+					http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+					return
+				}
+				defer reader.Close()
+				body = reader
+			}
+
+			r2 := r.Clone(r.Context())
+			r2.Body = io.NopCloser(body)
+			r2.ContentLength = -1
+			r2.Header.Del("Content-Encoding")
+			r2.Header.Del("Content-Length")
+
+			if maxSize <= 0 {
+				next.ServeHTTP(w, r2)
+				return
+			}
+
+			limited := &limitedBody{ReadCloser: http.MaxBytesReader(w, r2.Body, maxSize)}
+			r2.Body = limited
+			lw := &limitWriter{ResponseWriter: w, body: limited}
+			next.ServeHTTP(lw, r2)
+			if !lw.wroteHeader && limited.tooLarge {
+				lw.WriteHeader(http.StatusRequestEntityTooLarge)
+			}
+		})
+	}
+}
+
+// limitedBody is a request body that records whether it was read past its
+// limit.
+type limitedBody struct {
+	io.ReadCloser
+	tooLarge bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		b.tooLarge = true
+	}
+
+	return n, err
+}
+
+// limitWriter is an http.ResponseWriter that answers with 413 Request Entity
+// Too Large instead of the handler's response once the request body was read
+// past its limit.
+type limitWriter struct {
+	http.ResponseWriter
+	body        *limitedBody
+	wroteHeader bool
+	discard     bool
+}
+
+func (lw *limitWriter) WriteHeader(statusCode int) {
+	if lw.wroteHeader {
+		return
+	}
+	lw.wroteHeader = true
+
+	if lw.body.tooLarge {
+		lw.discard = true
+		http.Error(lw.ResponseWriter, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	lw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (lw *limitWriter) Write(p []byte) (int, error) {
+	if !lw.wroteHeader {
+		lw.WriteHeader(http.StatusOK)
+	}
+	if lw.discard {
+		return len(p), nil
+	}
+
+	return lw.ResponseWriter.Write(p)
+}
+
+// Flush writes any buffered data of the underlying ResponseWriter to the
+// client.
+func (lw *limitWriter) Flush() {
+	if !lw.wroteHeader {
+		lw.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := lw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// contentCodings returns the content codings of a Content-Encoding header in
+// the order they were applied, leaving out identity.
+func contentCodings(contentEncoding string) []string {
+	codings := make([]string, 0)
+	for _, coding := range strings.Split(contentEncoding, ",") {
+		coding = strings.TrimSpace(coding)
+		if coding != "" && !strings.EqualFold(coding, "identity") {
+			codings = append(codings, coding)
+		}
+	}
+
+	return codings
+}
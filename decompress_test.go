@@ -0,0 +1,148 @@
+package negotiator_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"github.com/noelukwa/negotiator"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func gzipBytes(p []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(p)
+	zw.Close()
+	return buf.Bytes()
+}
+
+func zlibBytes(p []byte) []byte {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(p)
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	payload := []byte(`{"event":"telemetry"}`)
+
+	tests := []struct {
+		name            string
+		contentEncoding string
+		body            []byte
+		status          int
+		acceptEncoding  string
+	}{
+		{"should pass plain bodies through", "", payload, http.StatusOK, ""},
+		{"should pass identity bodies through", "identity", payload, http.StatusOK, ""},
+		{"should decode gzip", "gzip", gzipBytes(payload), http.StatusOK, ""},
+		{"should decode deflate", "Deflate", zlibBytes(payload), http.StatusOK, ""},
+		{"should decode stacked codings in reverse order", "gzip, deflate", zlibBytes(gzipBytes(payload)), http.StatusOK, ""},
+		{"should refuse unknown codings", "gzip, br", gzipBytes(payload), http.StatusUnsupportedMediaType, "gzip, deflate"},
+		{"should refuse corrupt bodies", "gzip", payload, http.StatusBadRequest, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var body []byte
+			var contentEncoding string
+			handler := negotiator.Decompress(nil, 0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ = io.ReadAll(r.Body)
+				contentEncoding = r.Header.Get("Content-Encoding")
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(test.body))
+			req.Header.Set("Content-Encoding", test.contentEncoding)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != test.status {
+				t.Fatalf("Expected status %d, got %d", test.status, rec.Code)
+			}
+			if acceptEncoding := rec.Header().Get("Accept-Encoding"); acceptEncoding != test.acceptEncoding {
+				t.Errorf("Expected Accept-Encoding %q, got %q", test.acceptEncoding, acceptEncoding)
+			}
+			if test.status != http.StatusOK {
+				return
+			}
+
+			if !bytes.Equal(body, payload) {
+				t.Errorf("Expected body %q, got %q", payload, body)
+			}
+			if contentEncoding != "" && contentEncoding != "identity" {
+				t.Errorf("Expected no Content-Encoding, got %q", contentEncoding)
+			}
+		})
+	}
+}
+
+func TestDecompress_Registry(t *testing.T) {
+	registry := negotiator.NewEncoderRegistry(negotiator.Encoder{
+		Name: "x-upper",
+		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+			p, err := io.ReadAll(r)
+			return io.NopCloser(bytes.NewReader(bytes.ToUpper(p))), err
+		},
+	})
+
+	var body []byte
+	handler := negotiator.Decompress(registry, 0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte("hello")))
+	req.Header.Set("Content-Encoding", "x-upper")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if string(body) != "HELLO" {
+		t.Errorf("Expected the body to pass through the decoder, got %q", body)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(gzipBytes([]byte("hello"))))
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnsupportedMediaType || rec.Header().Get("Accept-Encoding") != "x-upper" {
+		t.Errorf("Expected 415 with Accept-Encoding x-upper, got %d with %q", rec.Code, rec.Header().Get("Accept-Encoding"))
+	}
+}
+
+func TestDecompress_MaxSize(t *testing.T) {
+	payload := bytes.Repeat([]byte("a"), 1024)
+
+	tests := []struct {
+		name    string
+		maxSize int64
+		status  int
+	}{
+		{"should accept bodies up to the limit", 1024, http.StatusOK},
+		{"should refuse bodies past the limit", 1023, http.StatusRequestEntityTooLarge},
+		{"should not limit without a maximum size", 0, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := negotiator.Decompress(nil, test.maxSize)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if _, err := io.ReadAll(r.Body); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				io.WriteString(w, "ok")
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(gzipBytes(payload)))
+			req.Header.Set("Content-Encoding", "gzip")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != test.status {
+				t.Errorf("Expected status %d, got %d", test.status, rec.Code)
+			}
+		})
+	}
+}
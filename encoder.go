@@ -0,0 +1,149 @@
+package negotiator
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Encoder is a content coding the server can compress responses and
+// decompress requests with, such as gzip, or br and zstd from a third-party
+// library.
+type Encoder struct {
+	// Name is the content coding, as used in the Accept-Encoding and
+	// Content-Encoding headers.
+	Name string
+	// NewWriter returns a writer that compresses to w at the given level. A
+	// level of zero asks for the default level of the coding.
+	NewWriter func(w io.Writer, level int) (io.WriteCloser, error)
+	// NewReader returns a reader that decompresses r. When nil, request
+	// bodies in the coding are refused.
+	NewReader func(r io.Reader) (io.ReadCloser, error)
+	// Rank is the server's preference for the coding. Among codings the client
+	// accepts equally, the one with the highest rank is chosen.
+	Rank int
+	// Level is the compression level passed to NewWriter. Zero means the
+	// level of the Compression, or the default level of the coding.
+	Level int
+}
+
+// EncoderRegistry holds encoders by content coding name. It is safe for
+// concurrent use.
+type EncoderRegistry struct {
+	mu       sync.RWMutex
+	encoders map[string]Encoder
+}
+
+// NewEncoderRegistry returns a registry holding the encoders.
+func NewEncoderRegistry(encoders ...Encoder) *EncoderRegistry {
+	registry := &EncoderRegistry{encoders: make(map[string]Encoder)}
+	for _, encoder := range encoders {
+		registry.Register(encoder)
+	}
+
+	return registry
+}
+
+// DefaultEncoders holds the gzip and deflate encoders of the standard library,
+// preferring gzip. It is used when no registry is configured.
+var DefaultEncoders = NewEncoderRegistry(
+	Encoder{
+		Name: "gzip",
+		NewWriter: func(w io.Writer, level int) (io.WriteCloser, error) {
+			if level == 0 {
+				level = gzip.DefaultCompression
+			}
+			return gzip.NewWriterLevel(w, level)
+		},
+		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+		Rank: 2,
+	},
+	Encoder{
+		// The deflate coding is the zlib format, see RFC 9110 section 8.4.1.2.
+		Name: "deflate",
+		NewWriter: func(w io.Writer, level int) (io.WriteCloser, error) {
+			if level == 0 {
+				level = zlib.DefaultCompression
+			}
+			return zlib.NewWriterLevel(w, level)
+		},
+		NewReader: zlib.NewReader,
+		Rank:      1,
+	},
+)
+
+// Register adds an encoder, replacing any encoder of the same name.
+func (r *EncoderRegistry) Register(encoder Encoder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.encoders[strings.ToLower(encoder.Name)] = encoder
+}
+
+// Lookup returns the encoder of a content coding, ignoring case.
+func (r *EncoderRegistry) Lookup(name string) (Encoder, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	encoder, ok := r.encoders[strings.ToLower(name)]
+	return encoder, ok
+}
+
+// Names returns the names of the registered content codings, from the highest
+// rank down.
+func (r *EncoderRegistry) Names() []string {
+	r.mu.RLock()
+	encoders := make([]Encoder, 0, len(r.encoders))
+	for _, encoder := range r.encoders {
+		encoders = append(encoders, encoder)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(encoders, func(i, j int) bool {
+		if encoders[i].Rank != encoders[j].Rank {
+			return encoders[i].Rank > encoders[j].Rank
+		}
+		return encoders[i].Name < encoders[j].Name
+	})
+
+	names := make([]string, len(encoders))
+	for i, encoder := range encoders {
+		names[i] = encoder.Name
+	}
+
+	return names
+}
+
+// decoderNames returns the names of the registered content codings that can
+// be decoded, from the highest rank down.
+func (r *EncoderRegistry) decoderNames() []string {
+	names := make([]string, 0)
+	for _, name := range r.Names() {
+		if encoder, ok := r.Lookup(name); ok && encoder.NewReader != nil {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// rank returns the rank of a content coding, or zero when it is not
+// registered.
+func (r *EncoderRegistry) rank(name string) int {
+	encoder, _ := r.Lookup(name)
+	return encoder.Rank
+}
+
+// WithEncoders makes ParseEncoding and Encoding offer the codings of the
+// registry when called without available encodings, and break ties between
+// encodings of equal quality by their rank in the registry.
+func WithEncoders(registry *EncoderRegistry) Option {
+	return func(n *Negotiator) {
+		n.encoders = registry
+	}
+}
@@ -0,0 +1,122 @@
+package negotiator_test
+
+import (
+	"github.com/noelukwa/negotiator"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// upperWriter stands in for a real compressor by upper-casing its input.
+type upperWriter struct {
+	w io.Writer
+}
+
+func (uw upperWriter) Write(p []byte) (int, error) {
+	return uw.w.Write([]byte(strings.ToUpper(string(p))))
+}
+
+func (uw upperWriter) Close() error {
+	return nil
+}
+
+func upperEncoder(name string, rank int, levels *[]int) negotiator.Encoder {
+	return negotiator.Encoder{
+		Name: name,
+		NewWriter: func(w io.Writer, level int) (io.WriteCloser, error) {
+			if levels != nil {
+				*levels = append(*levels, level)
+			}
+			return upperWriter{w: w}, nil
+		},
+		Rank: rank,
+	}
+}
+
+func TestEncoderRegistry(t *testing.T) {
+	registry := negotiator.NewEncoderRegistry(upperEncoder("gzip", 1, nil), upperEncoder("br", 3, nil), upperEncoder("zstd", 2, nil))
+
+	names := registry.Names()
+	expected := []string{"br", "zstd", "gzip"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+
+	if _, ok := registry.Lookup("BR"); !ok {
+		t.Errorf("Expected to find br ignoring case")
+	}
+	if _, ok := registry.Lookup("compress"); ok {
+		t.Errorf("Expected not to find compress")
+	}
+}
+
+func TestNegotiator_ParseEncoding_WithEncoders(t *testing.T) {
+	registry := negotiator.NewEncoderRegistry(upperEncoder("gzip", 1, nil), upperEncoder("deflate", 0, nil), upperEncoder("br", 2, nil))
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		expected       []string
+	}{
+		{"should break ties by rank", "gzip, deflate, br", []string{"br", "gzip", "deflate"}},
+		{"should prefer quality over rank", "gzip, br;q=0.5", []string{"gzip", "br"}},
+		{"should offer the registry without a header", "", []string{"br", "gzip", "deflate"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if test.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", test.acceptEncoding)
+			}
+
+			actual := negotiator.New(req, negotiator.WithEncoders(registry)).ParseEncoding()
+			if strings.Join(actual, ",") != strings.Join(test.expected, ",") {
+				t.Errorf("Expected %v Encodings , got %v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCompression_Encoders(t *testing.T) {
+	var levels []int
+	brotli := upperEncoder("br", 3, &levels)
+	brotli.Level = 11
+	compression := &negotiator.Compression{
+		Level:    5,
+		Encoders: negotiator.NewEncoderRegistry(brotli, upperEncoder("x-test", 1, &levels)),
+	}
+
+	handler := compression.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello")
+	}))
+
+	tests := []struct {
+		acceptEncoding string
+		expected       string
+		level          int
+	}{
+		{"gzip, br, x-test", "br", 11},
+		{"x-test", "x-test", 5},
+	}
+
+	for _, test := range tests {
+		levels = nil
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", test.acceptEncoding)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if contentEncoding := rec.Header().Get("Content-Encoding"); contentEncoding != test.expected {
+			t.Errorf("Expected Content-Encoding %s, got %s", test.expected, contentEncoding)
+		}
+		if rec.Body.String() != "HELLO" {
+			t.Errorf("Expected the body to pass through the encoder, got %q", rec.Body.String())
+		}
+		if len(levels) != 1 || levels[0] != test.level {
+			t.Errorf("Expected level %d, got %v", test.level, levels)
+		}
+	}
+}
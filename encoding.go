@@ -3,41 +3,146 @@ package negotiator
 // ParseEncoding parses the Accept-Encoding header and returns a list of encodings
 // accepted by the client, sorted by priority.
 import (
+	"errors"
 	"sort"
-	"strconv"
 	"strings"
 )
 
+// ErrNoAcceptableEncoding is returned when the client refuses every available
+// content coding, including the identity coding.
+var ErrNoAcceptableEncoding = errors.New("negotiator: no acceptable encoding")
+
 type Encoding struct {
 	Name    string
 	Quality float64
 	Index   int
+	// Range is the encoding from the Accept-Encoding header that matched.
+	Range string
 }
 
 func (n *Negotiator) ParseEncoding(available ...string) []string {
-	acceptEncoding := n.req.Header.Get("Accept-Encoding")
-	if acceptEncoding == "" {
+	if len(available) == 0 && n.encoders != nil {
+		available = n.encoders.Names()
+	}
+
+	if _, ok := n.acceptEncoding(); !ok {
 		return available // If no header is found, return the available encodings as is.
 	}
 
-	parsedEncodings := parseAcceptEncoding(acceptEncoding)
-	filteredEncodings := filterEncodings(parsedEncodings, available)
+	filteredEncodings := n.EncodingMatches(available...)
+
+	// Extract the encoding names
+	result := make([]string, len(filteredEncodings))
+	for i, encoding := range filteredEncodings {
+		result[i] = encoding.Name
+	}
+
+	return result
+}
+
+// EncodingMatches returns the available encodings that are acceptable to the
+// client, sorted by priority. Each result carries the encoding from the
+// Accept-Encoding header that matched it, its position in the header and its
+// quality. A "*" in the header matches every encoding not named elsewhere in
+// the header. The identity coding is acceptable unless refused with
+// "identity;q=0" or "*;q=0", and ranks after the encodings the client named.
+// An empty header accepts the identity coding only.
+func (n *Negotiator) EncodingMatches(available ...string) []Encoding {
+	return n.NegotiateEncodings(Offers(available...)...)
+}
+
+// NegotiateEncodings is like EncodingMatches, but weighs each offer by its
+// source quality.
+func (n *Negotiator) NegotiateEncodings(offers ...Offer) []Encoding {
+	var filteredEncodings []Encoding
 
-	// Sort encodings based on quality and order in the header
+	acceptEncoding, ok := n.acceptEncoding()
+	if !ok || strings.TrimSpace(acceptEncoding) == "" {
+		// If no header is found, every offered encoding is acceptable, while
+		// an empty header accepts the identity coding only.
+		filteredEncodings = make([]Encoding, 0, len(offers))
+		for _, offer := range offers {
+			if ok && !strings.EqualFold(offer.Value, "identity") {
+				continue
+			}
+			if quality := offer.sourceQuality(); quality > 0 {
+				filteredEncodings = append(filteredEncodings, Encoding{Name: offer.Value, Quality: quality, Index: -1})
+			}
+		}
+	} else {
+		parsedEncodings := parseAcceptEncoding(acceptEncoding)
+		filteredEncodings = filterEncodings(parsedEncodings, offers)
+	}
+
+	// Sort encodings based on quality, rank in the registry and order in the header
 	sort.SliceStable(filteredEncodings, func(i, j int) bool {
 		if filteredEncodings[i].Quality != filteredEncodings[j].Quality {
 			return filteredEncodings[i].Quality > filteredEncodings[j].Quality // Higher quality first
 		}
+		if n.encoders != nil {
+			if rankI, rankJ := n.encoders.rank(filteredEncodings[i].Name), n.encoders.rank(filteredEncodings[j].Name); rankI != rankJ {
+				return rankI > rankJ // Server-preferred coding for same quality
+			}
+		}
 		return filteredEncodings[i].Index < filteredEncodings[j].Index // Original order for same quality
 	})
 
-	// Extract the encoding names
-	result := make([]string, len(filteredEncodings))
-	for i, encoding := range filteredEncodings {
-		result[i] = encoding.Name
+	return filteredEncodings
+}
+
+// AcceptedEncodings is like NegotiateEncodings, but with WithStrictParsing it
+// returns a *ParseError when the Accept-Encoding header is malformed.
+func (n *Negotiator) AcceptedEncodings(offers ...Offer) ([]Encoding, error) {
+	if err := n.checkStrict("Accept-Encoding"); err != nil {
+		return nil, err
 	}
 
-	return result
+	return n.NegotiateEncodings(offers...), nil
+}
+
+// Encoding returns the available content coding the client prefers most, or
+// the most preferred coding of the registry set with WithEncoders when none
+// are given. The identity coding, meaning no coding, is always available, and is returned
+// when the client names none of the available codings. When the client
+// refuses every available coding as well as the identity coding, Encoding
+// returns ErrNoAcceptableEncoding, which a server answers with 406 Not
+// Acceptable.
+func (n *Negotiator) Encoding(available ...string) (Encoding, error) {
+	if err := n.checkStrict("Accept-Encoding"); err != nil {
+		return Encoding{}, err
+	}
+
+	if len(available) == 0 && n.encoders != nil {
+		available = n.encoders.Names()
+	}
+
+	offers := Offers(available...)
+	hasIdentity := false
+	for _, offer := range offers {
+		hasIdentity = hasIdentity || strings.EqualFold(offer.Value, "identity")
+	}
+	if !hasIdentity {
+		offers = append(offers, Offer{Value: "identity", Quality: 1})
+	}
+
+	encodings := n.NegotiateEncodings(offers...)
+	if len(encodings) == 0 {
+		return Encoding{}, ErrNoAcceptableEncoding
+	}
+
+	return encodings[0], nil
+}
+
+// acceptEncoding returns the Accept-Encoding header, and whether the request
+// has one, since an empty header means something else than no header.
+// See https://www.rfc-editor.org/rfc/rfc9110#section-12.5.3
+func (n *Negotiator) acceptEncoding() (string, bool) {
+	values := n.req.Header.Values("Accept-Encoding")
+	if len(values) == 0 {
+		return "", false
+	}
+
+	return values[0], true
 }
 
 func parseAcceptEncoding(input string) []Encoding {
@@ -45,34 +150,69 @@ func parseAcceptEncoding(input string) []Encoding {
 	encodings := make([]Encoding, 0, len(rawEncodings))
 
 	for i, rawEncoding := range rawEncodings {
-		parts := strings.Split(rawEncoding, ";q=")
-		name := strings.TrimSpace(parts[0])
-		quality := 1.0 // Default quality value
-		if len(parts) > 1 {
-			q, err := strconv.ParseFloat(parts[1], 64)
-			if err == nil {
-				quality = q
-			}
-		}
-		encodings = append(encodings, Encoding{Name: name, Quality: quality, Index: i})
+		name, quality, _ := splitQuality(rawEncoding)
+		encodings = append(encodings, Encoding{Name: name, Quality: quality, Index: i, Range: name})
 	}
 
 	return encodings
 }
 
-func filterEncodings(parsedEncodings []Encoding, available []string) []Encoding {
-	availableSet := make(map[string]struct{})
-	for _, encoding := range available {
-		availableSet[strings.ToLower(encoding)] = struct{}{}
+// unlistedIdentityQuality is the quality given to the identity coding when
+// the Accept-Encoding header names neither it nor "*". A q-value has at most
+// three decimal digits, so 0.001 is the lowest quality a client can accept a
+// coding with, and identity, which takes the position after the last encoding
+// of the header, ranks after every coding the client asked for.
+// See https://www.rfc-editor.org/rfc/rfc9110#section-12.4.2
+const unlistedIdentityQuality = 0.001
+
+// filterEncodings returns the offered encodings, each with the quality of the
+// best encoding in the header that names it multiplied by the offer's source
+// quality. An offer named nowhere in the header takes the quality of "*", and
+// the identity coding is acceptable even when the header does not mention it.
+// An encoding refused with a q-value of 0 is left out, even if it is listed
+// again with a higher q-value.
+// See https://www.rfc-editor.org/rfc/rfc9110#section-12.5.3 for details.
+func filterEncodings(parsedEncodings []Encoding, offers []Offer) []Encoding {
+	filteredEncodings := make([]Encoding, 0, len(offers))
+	for _, offer := range offers {
+		match := bestEncoding(parsedEncodings, offer.Value, func(name string) bool {
+			return strings.EqualFold(name, offer.Value)
+		})
+		if match.Quality < 0 {
+			match = bestEncoding(parsedEncodings, offer.Value, func(name string) bool {
+				return name == "*"
+			})
+		}
+		if match.Quality < 0 && strings.EqualFold(offer.Value, "identity") {
+			match = Encoding{Name: offer.Value, Quality: unlistedIdentityQuality, Index: len(parsedEncodings)}
+		}
+
+		if match.Quality *= offer.sourceQuality(); match.Quality > 0 {
+			filteredEncodings = append(filteredEncodings, match)
+		}
 	}
 
-	filteredEncodings := make([]Encoding, 0, len(parsedEncodings))
+	return filteredEncodings
+}
+
+// bestEncoding returns the offered encoding with the highest quality among the
+// encodings in the header that match it, or a quality of -1 when none does. A
+// refusal with a q-value of 0 wins over every other match.
+func bestEncoding(parsedEncodings []Encoding, offer string, matches func(name string) bool) Encoding {
+	match := Encoding{Name: offer, Quality: -1}
 	for _, encoding := range parsedEncodings {
-		_, exists := availableSet[strings.ToLower(encoding.Name)]
-		if exists {
-			filteredEncodings = append(filteredEncodings, encoding)
+		if !matches(encoding.Name) {
+			continue
+		}
+		if encoding.Quality <= 0 {
+			return Encoding{Name: offer, Quality: 0, Index: encoding.Index, Range: encoding.Name}
+		}
+		if encoding.Quality > match.Quality {
+			match.Quality = encoding.Quality
+			match.Index = encoding.Index
+			match.Range = encoding.Name
 		}
 	}
 
-	return filteredEncodings
+	return match
 }
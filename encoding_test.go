@@ -105,6 +105,18 @@ func TestNegotiator_ParseEncoding(t *testing.T) {
 			[]string{"gzip", "identity", "*"},
 			nil,
 		},
+		{
+			"should not return a refused encoding",
+			"gzip;q=0, deflate",
+			[]string{"deflate"},
+			[]string{"gzip", "deflate"},
+		},
+		{
+			"should keep a refused encoding refused",
+			"gzip;q=0, deflate;q=0.5, gzip",
+			[]string{"deflate"},
+			[]string{"gzip", "deflate"},
+		},
 	}
 
 	for _, test := range tests {
@@ -138,3 +150,108 @@ func TestNegotiator_ParseEncoding(t *testing.T) {
 	}
 
 }
+
+func TestNegotiator_EncodingMatches(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "deflate;q=0.5, GZIP")
+
+	matches := negotiator.New(req).EncodingMatches("gzip", "deflate", "br")
+
+	expected := []negotiator.Encoding{
+		{Name: "gzip", Quality: 1, Index: 1, Range: "GZIP"},
+		{Name: "deflate", Quality: 0.5, Index: 0, Range: "deflate"},
+	}
+	if len(matches) != len(expected) {
+		t.Fatalf("Expected %v Encodings , got %v", expected, matches)
+	}
+	for i, v := range matches {
+		if v != expected[i] {
+			t.Errorf("Expected %v Encoding , got %v", expected[i], v)
+		}
+	}
+}
+
+func TestNegotiator_ParseEncoding_Wildcards(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		expected       []string
+		available      []string
+	}{
+		{"should accept every encoding for *", "*", []string{"gzip", "br", "identity"}, []string{"gzip", "br", "identity"}},
+		{"should prefer named encodings over *", "*;q=0.5, br", []string{"br", "gzip", "identity"}, []string{"gzip", "br", "identity"}},
+		{"should refuse unnamed encodings with *;q=0", "gzip, *;q=0", []string{"gzip"}, []string{"gzip", "br", "identity"}},
+		{"should accept identity when not mentioned", "gzip;q=0.2", []string{"gzip", "identity"}, []string{"identity", "gzip", "br"}},
+		{"should refuse identity", "gzip, identity;q=0", []string{"gzip"}, []string{"identity", "gzip"}},
+		{"should let identity override *;q=0", "*;q=0, identity", []string{"identity"}, []string{"gzip", "identity"}},
+		{"should refuse with a trailing space", "gzip;q=0 , br", []string{"br", "identity"}, []string{"gzip", "br", "identity"}},
+		{"should read q-values with spaces and in upper case", "gzip ; Q=0.5, br; q=0.8", []string{"br", "gzip", "identity"}, []string{"gzip", "br", "identity"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", test.acceptEncoding)
+
+			actual := negotiator.New(req).ParseEncoding(test.available...)
+			if len(actual) != len(test.expected) {
+				t.Fatalf("Expected %v Encodings , got %v", test.expected, actual)
+			}
+			for i, v := range actual {
+				if v != test.expected[i] {
+					t.Errorf("Expected %s Encoding , got %s", test.expected[i], v)
+				}
+			}
+		})
+	}
+}
+
+func TestNegotiator_Encoding(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		available      []string
+		expected       string
+		err            error
+	}{
+		{"should return the preferred encoding", "br;q=0.5, gzip", []string{"br", "gzip"}, "gzip", nil},
+		{"should fall back to identity", "compress", []string{"br", "gzip"}, "identity", nil},
+		{"should return the first encoding without a header", "", []string{"br", "gzip"}, "br", nil},
+		{"should refuse everything", "gzip;q=0, *;q=0", []string{"gzip"}, "", negotiator.ErrNoAcceptableEncoding},
+		{"should refuse identity", "identity;q=0, br", []string{"gzip"}, "", negotiator.ErrNoAcceptableEncoding},
+		{"should refuse everything with spaces around parameters", "identity; q=0, *; q=0", []string{"gzip"}, "", negotiator.ErrNoAcceptableEncoding},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if test.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", test.acceptEncoding)
+			}
+
+			encoding, err := negotiator.New(req).Encoding(test.available...)
+			if err != test.err {
+				t.Fatalf("Expected error %v, got %v", test.err, err)
+			}
+			if encoding.Name != test.expected {
+				t.Errorf("Expected %s Encoding , got %s", test.expected, encoding.Name)
+			}
+		})
+	}
+}
+
+func TestNegotiator_EncodingEmptyHeader(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "")
+
+	if actual := negotiator.New(req).ParseEncoding("br", "gzip", "identity"); len(actual) != 1 || actual[0] != "identity" {
+		t.Errorf("Expected [identity] Encodings , got %v", actual)
+	}
+
+	encoding, err := negotiator.New(req).Encoding("br", "gzip")
+	if err != nil || encoding.Name != "identity" {
+		t.Errorf("Expected identity Encoding , got %s (%v)", encoding.Name, err)
+	}
+}
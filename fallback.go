@@ -0,0 +1,167 @@
+package negotiator
+
+import "errors"
+
+// ErrNoAcceptableLanguage is returned when none of the available languages is
+// acceptable to the client and no default language is configured.
+var ErrNoAcceptableLanguage = errors.New("negotiator: no acceptable language")
+
+// FallbackPolicy describes what to do when none of the available languages
+// matches the Accept-Language header.
+type FallbackPolicy struct {
+	// Scheme is used to match the Accept-Language header against the
+	// available languages before falling back. It defaults to ExactMatch.
+	Scheme LanguageScheme
+	// Chains maps a language range to the languages to try in its place, in
+	// order, such as pt-BR to pt, es and en.
+	Chains map[string][]string
+	// Default is the language used when nothing else matches.
+	Default string
+}
+
+// LanguageSource tells how the language chosen by Language was found.
+type LanguageSource int
+
+const (
+	// LanguageMatched means the language matched the Accept-Language header.
+	LanguageMatched LanguageSource = iota
+	// LanguageFallback means the language was found through a fallback chain.
+	LanguageFallback
+	// LanguageDefault means the default language was used.
+	LanguageDefault
+)
+
+// LanguageResult is the language chosen by Language.
+type LanguageResult struct {
+	Lang
+	Source LanguageSource
+}
+
+// WithLanguageFallback sets the fallback policy used by Language.
+func WithLanguageFallback(policy FallbackPolicy) Option {
+	return func(n *Negotiator) {
+		n.fallback = policy
+	}
+}
+
+// Language returns the available language the client prefers most. When none
+// matches, the fallback chains of the client's language ranges are walked in
+// order of preference, and then the default language is used. Languages the
+// client refused with a q-value of 0 are never chosen through a fallback
+// chain, and neither are languages the header does not name when it refuses
+// "*". Without an Accept-Language header the default language is used, or the
+// first available language when there is none. A default language that is not
+// available is replaced by the first available language.
+func (n *Negotiator) Language(available ...string) (LanguageResult, error) {
+	acceptLanguage := n.req.Header.Get("Accept-Language")
+	if acceptLanguage == "" {
+		if n.fallback.Default == "" && len(available) > 0 {
+			return LanguageResult{Lang: Lang{Name: available[0], Quality: 1, Index: -1}, Source: LanguageDefault}, nil
+		}
+		return n.defaultLanguage(available)
+	}
+
+	matches, err := n.negotiateLanguages(n.fallback.Scheme, Offers(available...))
+	if err != nil {
+		return LanguageResult{}, err
+	}
+
+	if len(matches) > 0 {
+		return LanguageResult{Lang: matches[0], Source: LanguageMatched}, nil
+	}
+
+	parsedLanguages, err := splitLanguages(acceptLanguage)
+	if err != nil {
+		return LanguageResult{}, err
+	}
+
+	if result, ok := n.fallbackLanguage(parsedLanguages, available); ok {
+		return result, nil
+	}
+
+	return n.defaultLanguage(available)
+}
+
+// fallbackLanguage walks the fallback chains of the client's language ranges,
+// from the most preferred range down, and returns the first available
+// language the client did not refuse.
+func (n *Negotiator) fallbackLanguage(parsedLanguages []Lang, available []string) (LanguageResult, bool) {
+	chains := make(map[string][]string, len(n.fallback.Chains))
+	for languageRange, chain := range n.fallback.Chains {
+		chains[canonicalLanguage(languageRange)] = chain
+	}
+
+	ranges := append([]Lang(nil), parsedLanguages...)
+	sortLanguagesByPriority(ranges)
+
+	for _, languageRange := range ranges {
+		if languageRange.Quality <= 0 {
+			continue
+		}
+
+		for _, fallback := range chains[canonicalLanguage(languageRange.Name)] {
+			if isRefusedLanguage(parsedLanguages, fallback) {
+				continue
+			}
+
+			for _, name := range available {
+				if canonicalLanguage(name) == canonicalLanguage(fallback) {
+					return LanguageResult{
+						Lang: Lang{
+							Name:    name,
+							Quality: languageRange.Quality,
+							Range:   languageRange.Name,
+							Index:   languageRange.Index,
+						},
+						Source: LanguageFallback,
+					}, true
+				}
+			}
+		}
+	}
+
+	return LanguageResult{}, false
+}
+
+// defaultLanguage returns the default language as spelled in available, or
+// the first available language when the default is not available. It returns
+// ErrNoAcceptableLanguage when no default is configured.
+func (n *Negotiator) defaultLanguage(available []string) (LanguageResult, error) {
+	if n.fallback.Default == "" {
+		return LanguageResult{}, ErrNoAcceptableLanguage
+	}
+
+	name := n.fallback.Default
+	if len(available) > 0 {
+		name = available[0]
+		for _, language := range available {
+			if canonicalLanguage(language) == canonicalLanguage(n.fallback.Default) {
+				name = language
+				break
+			}
+		}
+	}
+
+	return LanguageResult{Lang: Lang{Name: name, Quality: 1, Index: -1}, Source: LanguageDefault}, nil
+}
+
+// isRefusedLanguage checks if the client refused a language with a q-value of
+// 0, either by name or, when the header does not name it, with "*;q=0".
+func isRefusedLanguage(parsedLanguages []Lang, name string) bool {
+	named, wildcardRefused := false, false
+	for _, lang := range parsedLanguages {
+		if lang.Name == "*" {
+			wildcardRefused = wildcardRefused || lang.Quality <= 0
+			continue
+		}
+		if canonicalLanguage(lang.Name) != canonicalLanguage(name) {
+			continue
+		}
+		if lang.Quality <= 0 {
+			return true
+		}
+		named = true
+	}
+
+	return !named && wildcardRefused
+}
@@ -0,0 +1,68 @@
+package negotiator_test
+
+import (
+	"errors"
+	"github.com/noelukwa/negotiator"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiator_Language(t *testing.T) {
+
+	policy := negotiator.FallbackPolicy{
+		Chains: map[string][]string{
+			"pt-BR": {"pt", "es", "en"},
+			"nb":    {"no", "nn", "da"},
+		},
+		Default: "en",
+	}
+
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		available      []string
+		expected       string
+		source         negotiator.LanguageSource
+	}{
+		{"should return a matching language", "pt-BR, en;q=0.5", []string{"en", "pt-BR"}, "pt-BR", negotiator.LanguageMatched},
+		{"should walk the fallback chain", "pt-br", []string{"en", "es", "de"}, "es", negotiator.LanguageFallback},
+		{"should walk the chain of the preferred range first", "de, nb;q=0.9, pt-BR;q=0.8", []string{"pt", "nn"}, "nn", negotiator.LanguageFallback},
+		{"should skip refused languages in the chain", "nb, nn;q=0", []string{"nn", "da"}, "da", negotiator.LanguageFallback},
+		{"should use the default language", "fr", []string{"de", "es", "en"}, "en", negotiator.LanguageDefault},
+		{"should use the default language after a refused wildcard", "pt-BR, *;q=0", []string{"es", "EN"}, "EN", negotiator.LanguageDefault},
+		{"should use the default language without a header", "", []string{"de", "es", "en"}, "en", negotiator.LanguageDefault},
+		{"should use the first language when the default is not available", "fr", []string{"de", "es"}, "de", negotiator.LanguageDefault},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Language", test.acceptLanguage)
+
+			result, err := negotiator.New(req, negotiator.WithLanguageFallback(policy)).Language(test.available...)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if result.Name != test.expected || result.Source != test.source {
+				t.Errorf("Expected %s Language from %d, got %s from %d", test.expected, test.source, result.Name, result.Source)
+			}
+		})
+	}
+}
+
+func TestNegotiator_LanguageWithoutFallback(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "fr")
+
+	if _, err := negotiator.New(req).Language("de", "es"); !errors.Is(err, negotiator.ErrNoAcceptableLanguage) {
+		t.Errorf("Expected %v error, got %v", negotiator.ErrNoAcceptableLanguage, err)
+	}
+
+	req.Header.Del("Accept-Language")
+
+	result, err := negotiator.New(req).Language("de", "es")
+	if err != nil || result.Name != "de" {
+		t.Errorf("Expected de Language, got %s (%v)", result.Name, err)
+	}
+}
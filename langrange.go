@@ -0,0 +1,127 @@
+package negotiator
+
+import "strings"
+
+// LanguageScheme selects how language ranges from the Accept-Language header
+// are matched against the available languages.
+// See https://www.rfc-editor.org/rfc/rfc4647
+type LanguageScheme int
+
+const (
+	// ExactMatch matches a language range only against the same language
+	// tag, ignoring case.
+	ExactMatch LanguageScheme = iota
+	// BasicFiltering matches a language range against every language tag it
+	// is a prefix of, so en matches en and en-US (RFC 4647 section 3.3.1).
+	BasicFiltering
+	// ExtendedFiltering is like BasicFiltering, but a * subtag in a language
+	// range matches any subtags, so de-*-DE matches de-Latn-DE and de-DE
+	// (RFC 4647 section 3.3.2).
+	ExtendedFiltering
+	// Lookup matches a language range against the language tag it falls back
+	// to by removing subtags from the end, so zh-Hant-TW falls back to
+	// zh-Hant and then zh (RFC 4647 section 3.4).
+	Lookup
+)
+
+// wildcardDistance is the distance of a match by the "*" language range, which
+// is looser than any other match.
+const wildcardDistance = 1 << 16
+
+// languageDistance reports how closely a language range matches a language
+// tag under the scheme, with 0 for an exact match and higher values for looser
+// matches, or -1 when the range does not match the tag. Both are canonicalized
+// first, so iw matches he and en-us matches en-US. Under every scheme, the "*"
+// range matches any tag, but more loosely than any other range.
+func languageDistance(scheme LanguageScheme, languageRange string, tag string) int {
+	if languageRange == "*" {
+		return wildcardDistance
+	}
+
+	languageRange, tag = canonicalLanguage(languageRange), canonicalLanguage(tag)
+
+	rangeSubtags := strings.Split(languageRange, "-")
+	tagSubtags := strings.Split(tag, "-")
+
+	switch scheme {
+	case BasicFiltering:
+		return basicFilteringDistance(rangeSubtags, tagSubtags)
+	case ExtendedFiltering:
+		return extendedFilteringDistance(rangeSubtags, tagSubtags)
+	case Lookup:
+		return lookupDistance(rangeSubtags, tagSubtags)
+	default:
+		if languageRange == tag {
+			return 0
+		}
+		return -1
+	}
+}
+
+// basicFilteringDistance matches when the range subtags are a prefix of the
+// tag subtags. The distance is the number of extra subtags in the tag.
+func basicFilteringDistance(rangeSubtags []string, tagSubtags []string) int {
+	if len(rangeSubtags) > len(tagSubtags) {
+		return -1
+	}
+
+	for i, subtag := range rangeSubtags {
+		if subtag != tagSubtags[i] {
+			return -1
+		}
+	}
+
+	return len(tagSubtags) - len(rangeSubtags)
+}
+
+// extendedFilteringDistance implements the extended filtering algorithm of RFC
+// 4647 section 3.3.2. The distance is the number of tag subtags not matched by
+// a concrete subtag of the range.
+func extendedFilteringDistance(rangeSubtags []string, tagSubtags []string) int {
+	if rangeSubtags[0] != "*" && rangeSubtags[0] != tagSubtags[0] {
+		return -1
+	}
+
+	matched := 1
+	if rangeSubtags[0] == "*" {
+		matched = 0
+	}
+
+	r, t := 1, 1
+	for r < len(rangeSubtags) {
+		switch {
+		case rangeSubtags[r] == "*":
+			r++
+		case t >= len(tagSubtags):
+			return -1
+		case rangeSubtags[r] == tagSubtags[t]:
+			matched++
+			r++
+			t++
+		case len(tagSubtags[t]) == 1:
+			return -1
+		default:
+			t++
+		}
+	}
+
+	return len(tagSubtags) - matched
+}
+
+// lookupDistance implements the fallback of the lookup algorithm of RFC 4647
+// section 3.4: subtags are removed from the end of the range, along with any
+// singleton left at its end, until it equals the tag. The distance is the
+// number of subtags removed.
+func lookupDistance(rangeSubtags []string, tagSubtags []string) int {
+	for end := len(rangeSubtags); end > 0; end-- {
+		if len(rangeSubtags[end-1]) == 1 {
+			continue
+		}
+
+		if end == len(tagSubtags) && basicFilteringDistance(rangeSubtags[:end], tagSubtags) == 0 {
+			return len(rangeSubtags) - end
+		}
+	}
+
+	return -1
+}
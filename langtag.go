@@ -0,0 +1,404 @@
+package negotiator
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidTag is returned for malformed or invalid BCP 47 language tags.
+var ErrInvalidTag = errors.New("negotiator: invalid language tag")
+
+// Tag is a BCP 47 language tag, such as en-US, zh-Hant-TW or
+// de-CH-1996-x-private.
+// See https://www.rfc-editor.org/rfc/rfc5646
+type Tag struct {
+	Language string
+	Extlang  string
+	Script   string
+	Region   string
+	Variants []string
+	// Extensions holds the extension sequences, such as u-co-phonebk.
+	Extensions []string
+	// PrivateUse holds the private use sequence, such as x-private.
+	PrivateUse string
+
+	// grandfathered holds a grandfathered tag such as i-default, which does
+	// not follow the grammar of the other tags.
+	grandfathered string
+}
+
+// ParseTag parses a well-formed BCP 47 language tag. The tag is returned with
+// normalized case, but is not canonicalized.
+func ParseTag(str string) (Tag, error) {
+	if tag, ok := grandfatheredTags[strings.ToLower(str)]; ok {
+		return Tag{grandfathered: tag}, nil
+	}
+
+	subtags := strings.Split(strings.ToLower(str), "-")
+	for _, subtag := range subtags {
+		if len(subtag) == 0 || len(subtag) > 8 || !isAlphanumeric(subtag) {
+			return Tag{}, fmt.Errorf("%w: %q", ErrInvalidTag, str)
+		}
+	}
+
+	var tag Tag
+	i := 0
+
+	if subtags[0] != "x" {
+		language := subtags[0]
+		if !isAlpha(language) || len(language) < 2 {
+			return Tag{}, fmt.Errorf("%w: %q: malformed language subtag %q", ErrInvalidTag, str, language)
+		}
+		tag.Language = language
+		i++
+
+		if len(language) <= 3 {
+			for extlangs := 0; i < len(subtags) && len(subtags[i]) == 3 && isAlpha(subtags[i]); extlangs++ {
+				if extlangs > 0 {
+					return Tag{}, fmt.Errorf("%w: %q: more than one extlang subtag", ErrInvalidTag, str)
+				}
+				tag.Extlang = subtags[i]
+				i++
+			}
+		}
+
+		if i < len(subtags) && len(subtags[i]) == 4 && isAlpha(subtags[i]) {
+			tag.Script = strings.ToUpper(subtags[i][:1]) + subtags[i][1:]
+			i++
+		}
+
+		if i < len(subtags) && ((len(subtags[i]) == 2 && isAlpha(subtags[i])) || (len(subtags[i]) == 3 && isDigits(subtags[i]))) {
+			tag.Region = strings.ToUpper(subtags[i])
+			i++
+		}
+
+		for ; i < len(subtags) && isVariant(subtags[i]); i++ {
+			for _, variant := range tag.Variants {
+				if variant == subtags[i] {
+					return Tag{}, fmt.Errorf("%w: %q: duplicate variant %q", ErrInvalidTag, str, variant)
+				}
+			}
+			tag.Variants = append(tag.Variants, subtags[i])
+		}
+
+		singletons := make(map[string]struct{})
+		for i < len(subtags) && len(subtags[i]) == 1 && subtags[i] != "x" {
+			singleton := subtags[i]
+			if _, ok := singletons[singleton]; ok {
+				return Tag{}, fmt.Errorf("%w: %q: duplicate extension %q", ErrInvalidTag, str, singleton)
+			}
+			singletons[singleton] = struct{}{}
+
+			start := i
+			for i++; i < len(subtags) && len(subtags[i]) > 1; i++ {
+			}
+			if i-start < 2 {
+				return Tag{}, fmt.Errorf("%w: %q: empty extension %q", ErrInvalidTag, str, singleton)
+			}
+			tag.Extensions = append(tag.Extensions, strings.Join(subtags[start:i], "-"))
+		}
+	}
+
+	if i < len(subtags) && subtags[i] == "x" {
+		if i == len(subtags)-1 {
+			return Tag{}, fmt.Errorf("%w: %q: empty private use sequence", ErrInvalidTag, str)
+		}
+		tag.PrivateUse = strings.Join(subtags[i:], "-")
+		i = len(subtags)
+	}
+
+	if i < len(subtags) {
+		return Tag{}, fmt.Errorf("%w: %q: unexpected subtag %q", ErrInvalidTag, str, subtags[i])
+	}
+
+	if err := tag.validate(); err != nil {
+		return Tag{}, fmt.Errorf("%w: %q: %v", ErrInvalidTag, str, err)
+	}
+
+	return tag, nil
+}
+
+// String returns the tag with normalized case, such as en-US or zh-Hant-TW.
+func (t Tag) String() string {
+	if t.grandfathered != "" {
+		return t.grandfathered
+	}
+
+	subtags := make([]string, 0, 4+len(t.Variants)+len(t.Extensions))
+	for _, subtag := range []string{t.Language, t.Extlang, t.Script, t.Region} {
+		if subtag != "" {
+			subtags = append(subtags, subtag)
+		}
+	}
+	subtags = append(subtags, t.Variants...)
+	subtags = append(subtags, t.Extensions...)
+	if t.PrivateUse != "" {
+		subtags = append(subtags, t.PrivateUse)
+	}
+
+	return strings.Join(subtags, "-")
+}
+
+// Canonicalize returns the canonical form of the tag, as described in RFC 5646
+// section 4.5: grandfathered and redundant tags are replaced by their preferred
+// values, deprecated language and region subtags by their replacements, an
+// extlang by the equivalent language, and extensions are sorted. Only the
+// extlangs of the embedded registry subset are replaced, as a well-formed
+// extlang missing from it may not be registered at all.
+func (t Tag) Canonicalize() Tag {
+	if t.grandfathered != "" {
+		if preferred, ok := preferredTags[strings.ToLower(t.grandfathered)]; ok {
+			tag, _ := ParseTag(preferred)
+			return tag
+		}
+		return t
+	}
+
+	canonical := t
+	canonical.Variants = append([]string(nil), t.Variants...)
+	canonical.Extensions = append([]string(nil), t.Extensions...)
+
+	// Redundant tags are registered for their language, extlang, script and
+	// region only, so the variants, extensions and private use carry over.
+	prefix := Tag{Language: t.Language, Extlang: t.Extlang, Script: t.Script, Region: t.Region}
+	if preferred, ok := preferredTags[strings.ToLower(prefix.String())]; ok {
+		preferredTag, _ := ParseTag(preferred)
+		canonical.Language, canonical.Extlang = preferredTag.Language, preferredTag.Extlang
+		canonical.Script, canonical.Region = preferredTag.Script, preferredTag.Region
+	}
+
+	if _, ok := extlangPrefixes[canonical.Extlang]; ok {
+		canonical.Language, canonical.Extlang = canonical.Extlang, ""
+	}
+
+	if preferred, ok := deprecatedLanguages[canonical.Language]; ok {
+		canonical.Language = preferred
+	}
+
+	if preferred, ok := deprecatedRegions[canonical.Region]; ok {
+		canonical.Region = preferred
+	}
+
+	sort.Strings(canonical.Extensions)
+
+	return canonical
+}
+
+// validate checks the tag against the embedded subset of the IANA Language
+// Subtag Registry: an extlang must follow its registered prefix. Extlangs
+// missing from the subset are accepted as they are.
+func (t Tag) validate() error {
+	if t.Extlang == "" {
+		return nil
+	}
+
+	if prefix, ok := extlangPrefixes[t.Extlang]; ok && prefix != t.Language {
+		return fmt.Errorf("extlang %q requires the language %q", t.Extlang, prefix)
+	}
+
+	return nil
+}
+
+// canonicalLanguage returns the canonical form of a language tag in lower case,
+// or the lower-cased input when it is not a well-formed tag, such as for the
+// "*" wildcard and extended language ranges.
+func canonicalLanguage(str string) string {
+	tag, err := ParseTag(str)
+	if err != nil {
+		return strings.ToLower(str)
+	}
+
+	return strings.ToLower(tag.Canonicalize().String())
+}
+
+// isVariant checks if a subtag is a variant: 5 to 8 alphanumerics, or 4
+// starting with a digit.
+func isVariant(subtag string) bool {
+	return len(subtag) >= 5 || (len(subtag) == 4 && subtag[0] >= '0' && subtag[0] <= '9')
+}
+
+// isAlpha checks if a lower-case string consists of letters only.
+func isAlpha(str string) bool {
+	for i := 0; i < len(str); i++ {
+		if str[i] < 'a' || str[i] > 'z' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isDigits checks if a string consists of digits only.
+func isDigits(str string) bool {
+	for i := 0; i < len(str); i++ {
+		if str[i] < '0' || str[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isAlphanumeric checks if a lower-case string consists of letters and digits only.
+func isAlphanumeric(str string) bool {
+	for i := 0; i < len(str); i++ {
+		if (str[i] < 'a' || str[i] > 'z') && (str[i] < '0' || str[i] > '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// The tables below are a subset of the IANA Language Subtag Registry.
+// See https://www.iana.org/assignments/language-subtag-registry
+
+// grandfatheredTags maps the lower-case grandfathered tags to their
+// normalized case.
+var grandfatheredTags = map[string]string{
+	"art-lojban":  "art-lojban",
+	"cel-gaulish": "cel-gaulish",
+	"en-gb-oed":   "en-GB-oed",
+	"i-ami":       "i-ami",
+	"i-bnn":       "i-bnn",
+	"i-default":   "i-default",
+	"i-enochian":  "i-enochian",
+	"i-hak":       "i-hak",
+	"i-klingon":   "i-klingon",
+	"i-lux":       "i-lux",
+	"i-mingo":     "i-mingo",
+	"i-navajo":    "i-navajo",
+	"i-pwn":       "i-pwn",
+	"i-tao":       "i-tao",
+	"i-tay":       "i-tay",
+	"i-tsu":       "i-tsu",
+	"no-bok":      "no-bok",
+	"no-nyn":      "no-nyn",
+	"sgn-be-fr":   "sgn-BE-FR",
+	"sgn-be-nl":   "sgn-BE-NL",
+	"sgn-ch-de":   "sgn-CH-DE",
+	"zh-guoyu":    "zh-guoyu",
+	"zh-hakka":    "zh-hakka",
+	"zh-min":      "zh-min",
+	"zh-min-nan":  "zh-min-nan",
+	"zh-xiang":    "zh-xiang",
+}
+
+// preferredTags maps the lower-case grandfathered and redundant tags that have
+// a preferred value to that value.
+var preferredTags = map[string]string{
+	"art-lojban":  "jbo",
+	"en-gb-oed":   "en-GB-oxendict",
+	"i-ami":       "ami",
+	"i-bnn":       "bnn",
+	"i-hak":       "hak",
+	"i-klingon":   "tlh",
+	"i-lux":       "lb",
+	"i-navajo":    "nv",
+	"i-pwn":       "pwn",
+	"i-tao":       "tao",
+	"i-tay":       "tay",
+	"i-tsu":       "tsu",
+	"no-bok":      "nb",
+	"no-nyn":      "nn",
+	"sgn-be-fr":   "sfb",
+	"sgn-be-nl":   "vgt",
+	"sgn-ch-de":   "sgg",
+	"zh-guoyu":    "cmn",
+	"zh-hakka":    "hak",
+	"zh-min-nan":  "nan",
+	"zh-xiang":    "hsn",
+	"sgn-br":      "bzs",
+	"sgn-co":      "csn",
+	"sgn-de":      "gsg",
+	"sgn-dk":      "dsl",
+	"sgn-es":      "ssp",
+	"sgn-fr":      "fsl",
+	"sgn-gb":      "bfi",
+	"sgn-gr":      "gss",
+	"sgn-ie":      "isg",
+	"sgn-it":      "ise",
+	"sgn-jp":      "jsl",
+	"sgn-mx":      "mfs",
+	"sgn-ni":      "ncs",
+	"sgn-nl":      "dse",
+	"sgn-no":      "nsl",
+	"sgn-pt":      "psr",
+	"sgn-se":      "swl",
+	"sgn-us":      "ase",
+	"sgn-za":      "sfs",
+	"zh-cmn-hans": "cmn-Hans",
+	"zh-cmn-hant": "cmn-Hant",
+}
+
+// deprecatedLanguages maps deprecated language subtags to their preferred values.
+var deprecatedLanguages = map[string]string{
+	"in":  "id",
+	"iw":  "he",
+	"ji":  "yi",
+	"jw":  "jv",
+	"mo":  "ro",
+	"ayx": "nun",
+	"bjd": "drl",
+	"ccq": "rki",
+	"cjr": "mom",
+	"cka": "cmr",
+	"cmk": "xch",
+	"drh": "khk",
+	"drw": "prs",
+	"gav": "dev",
+	"hrr": "jal",
+	"ibi": "opa",
+	"kgh": "kml",
+	"lcq": "ppr",
+	"mst": "mry",
+	"myt": "mry",
+	"sca": "hle",
+	"tie": "ras",
+	"tkk": "twm",
+	"tlw": "weo",
+	"tnf": "prs",
+	"ybd": "rki",
+	"yma": "lrr",
+}
+
+// deprecatedRegions maps deprecated region subtags to their preferred values.
+var deprecatedRegions = map[string]string{
+	"BU": "MM",
+	"DD": "DE",
+	"FX": "FR",
+	"TP": "TL",
+	"YD": "YE",
+	"ZR": "CD",
+}
+
+// extlangPrefixes maps extlang subtags to the language they must follow.
+var extlangPrefixes = map[string]string{
+	"ase": "sgn",
+	"bfi": "sgn",
+	"bzs": "sgn",
+	"cmn": "zh",
+	"csn": "sgn",
+	"dse": "sgn",
+	"dsl": "sgn",
+	"fsl": "sgn",
+	"gan": "zh",
+	"gsg": "sgn",
+	"hak": "zh",
+	"hsn": "zh",
+	"ise": "sgn",
+	"jsl": "sgn",
+	"lzh": "zh",
+	"nan": "zh",
+	"nsl": "sgn",
+	"ssp": "sgn",
+	"swl": "sgn",
+	"wuu": "zh",
+	"yue": "zh",
+	"arb": "ar",
+	"arz": "ar",
+	"apc": "ar",
+	"zsm": "ms",
+}
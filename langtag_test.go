@@ -0,0 +1,79 @@
+package negotiator_test
+
+import (
+	"errors"
+	"github.com/noelukwa/negotiator"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTag(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		tag       string
+		normal    string
+		canonical string
+	}{
+		{"should normalize case", "EN-us", "en-US", "en-US"},
+		{"should normalize script case", "ZH-hant-tw", "zh-Hant-TW", "zh-Hant-TW"},
+		{"should parse variants and extensions", "de-CH-1996-u-co-phonebk-a-bbb-x-private", "de-CH-1996-u-co-phonebk-a-bbb-x-private", "de-CH-1996-a-bbb-u-co-phonebk-x-private"},
+		{"should parse a numeric region", "es-419", "es-419", "es-419"},
+		{"should parse private use only", "X-Whatever", "x-whatever", "x-whatever"},
+		{"should replace a deprecated language", "iw", "iw", "he"},
+		{"should replace a deprecated region", "de-DD", "de-DD", "de-DE"},
+		{"should replace an irregular grandfathered tag", "i-klingon", "i-klingon", "tlh"},
+		{"should replace a regular grandfathered tag", "zh-min-nan", "zh-min-nan", "nan"},
+		{"should keep a grandfathered tag without preferred value", "I-DEFAULT", "i-default", "i-default"},
+		{"should replace a redundant tag", "sgn-US", "sgn-US", "ase"},
+		{"should replace an extlang", "zh-yue-HK", "zh-yue-HK", "yue-HK"},
+		{"should keep an extlang missing from the registry subset", "AR-aeb", "ar-aeb", "ar-aeb"},
+		{"should keep an unregistered extlang", "en-usa", "en-usa", "en-usa"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tag, err := negotiator.ParseTag(test.tag)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if tag.String() != test.normal {
+				t.Errorf("Expected %s tag, got %s", test.normal, tag.String())
+			}
+			if canonical := tag.Canonicalize().String(); canonical != test.canonical {
+				t.Errorf("Expected %s canonical tag, got %s", test.canonical, canonical)
+			}
+		})
+	}
+}
+
+func TestParseTagInvalid(t *testing.T) {
+	for _, tag := range []string{"", "e", "en_US", "en--US", "toolongtag", "de-1996-1996", "en-a-b", "en-u-co-u-nu", "en-x", "zh-yue-cmn", "ar-yue", "en-cmn", "en-US-!"} {
+		t.Run(tag, func(t *testing.T) {
+			if _, err := negotiator.ParseTag(tag); !errors.Is(err, negotiator.ErrInvalidTag) {
+				t.Errorf("Expected %v error, got %v", negotiator.ErrInvalidTag, err)
+			}
+		})
+	}
+}
+
+func TestNegotiator_ParseLanguagesCanonical(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "iw, i-klingon;q=0.5, EN-us;q=0.2")
+
+	actual, err := negotiator.New(req).ParseLanguages("en-US", "tlh", "he")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []string{"he", "tlh", "en-US"}
+	if len(actual) != len(expected) {
+		t.Fatalf("Expected %s Languages , got %s", expected, actual)
+	}
+	for i, v := range actual {
+		if v != expected[i] {
+			t.Errorf("Expected %s Language , got %s", expected[i], v)
+		}
+	}
+}
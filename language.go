@@ -3,51 +3,117 @@ package negotiator
 import (
 	"errors"
 	"sort"
-	"strconv"
 	"strings"
 )
 
+// Lang represents a language, either a language range from the
+// Accept-Language header or an available language matched against it.
 type Lang struct {
 	Name    string
 	Quality float64
+	// Range is the language range from the Accept-Language header that matched.
+	Range string
+	// Index is the position of Range in the Accept-Language header, or -1 when
+	// the header is missing.
+	Index int
 }
 
 // ParseLanguages parses the Accept-Language header and returns a list of languages
 // accepted by the client, sorted by priority.
 func (n *Negotiator) ParseLanguages(available ...string) ([]string, error) {
+	if err := n.checkStrict("Accept-Language"); err != nil {
+		return nil, err
+	}
+
 	acceptLanguage := n.req.Header.Get("Accept-Language")
 
 	if acceptLanguage == "" || len(available) == 0 || acceptLanguage == "*" {
 		return available, nil
 	}
 
+	preferredLanguages, err := n.LanguageMatches(available...)
+	if err != nil {
+		return nil, err
+	}
+
+	return getLanguages(preferredLanguages), nil
+}
+
+// LanguageMatches returns the available languages that are acceptable to the
+// client, sorted by priority. Each result carries the language range that
+// matched it, its position in the Accept-Language header and its quality.
+func (n *Negotiator) LanguageMatches(available ...string) ([]Lang, error) {
+	return n.NegotiateLanguages(Offers(available...)...)
+}
+
+// NegotiateLanguages is like LanguageMatches, but weighs each offer by its
+// source quality.
+func (n *Negotiator) NegotiateLanguages(offers ...Offer) ([]Lang, error) {
+	return n.negotiateLanguages(ExactMatch, offers)
+}
+
+// MatchLanguages is like LanguageMatches, but matches language ranges against
+// the available languages with the given scheme, such as BasicFiltering or
+// Lookup. Languages are sorted by quality, then by how closely they match.
+func (n *Negotiator) MatchLanguages(scheme LanguageScheme, available ...string) ([]Lang, error) {
+	return n.negotiateLanguages(scheme, Offers(available...))
+}
+
+// negotiateLanguages matches the Accept-Language header against the offers.
+func (n *Negotiator) negotiateLanguages(scheme LanguageScheme, offers []Offer) ([]Lang, error) {
+	if err := n.checkStrict("Accept-Language"); err != nil {
+		return nil, err
+	}
+
+	acceptLanguage := n.req.Header.Get("Accept-Language")
+
+	if acceptLanguage == "" || acceptLanguage == "*" {
+		languages := anyLanguages(acceptLanguage, offers)
+		sortLanguagesByPriority(languages)
+		return languages, nil
+	}
+
 	parsedLanguages, err := splitLanguages(acceptLanguage)
 	if err != nil {
 		return nil, err
 	}
 
-	preferredLanguages := findPreferredLanguages(parsedLanguages, available)
+	return findPreferredLanguages(parsedLanguages, offers, scheme), nil
+}
 
-	sortLanguagesByPriority(preferredLanguages)
+// anyLanguages returns every offered language in the server's order, for a
+// client that accepts any language.
+func anyLanguages(acceptLanguage string, offers []Offer) []Lang {
+	index := 0
+	if acceptLanguage == "" {
+		index = -1
+	}
 
-	return getLanguages(preferredLanguages), nil
+	languages := make([]Lang, 0, len(offers))
+	for _, offer := range offers {
+		if quality := offer.sourceQuality(); quality > 0 {
+			languages = append(languages, Lang{Name: offer.Value, Quality: quality, Range: acceptLanguage, Index: index})
+		}
+	}
+
+	return languages
 }
 
 // splitLanguages splits the Accept-Language header into individual languages with quality values.
+// Languages with a q-value of 0 are kept, as they exclude the languages they match.
 // It returns an error if a quality value is invalid.
 // See https://tools.ietf.org/html/rfc7231#section-5.3.5 for details.
 func splitLanguages(acceptLanguage string) ([]Lang, error) {
 	languages := strings.Split(acceptLanguage, ",")
 	parsedLanguages := make([]Lang, 0, len(languages))
 
-	for _, languageStr := range languages {
+	for i, languageStr := range languages {
 		language, err := parseLanguage(languageStr)
 		if err != nil {
 			return nil, err
 		}
-		if language.Quality > 0 {
-			parsedLanguages = append(parsedLanguages, *language)
-		}
+		language.Index = i
+		parsedLanguages = append(parsedLanguages, *language)
 	}
 
 	return parsedLanguages, nil
@@ -56,40 +122,91 @@ func splitLanguages(acceptLanguage string) ([]Lang, error) {
 // parseLanguage parses a language string into a Lang struct.
 // It returns an error if a quality value is invalid.
 func parseLanguage(languageStr string) (*Lang, error) {
-	language := strings.Split(languageStr, ";q=")
-	if len(language) == 1 {
-		return &Lang{Name: strings.TrimSpace(language[0]), Quality: 1}, nil
-	}
-
-	quality, err := strconv.ParseFloat(strings.TrimSpace(language[1]), 64)
+	name, quality, err := splitQuality(languageStr)
 	if err != nil {
 		return nil, errors.New("failed to parse quality value")
 	}
 
-	return &Lang{Name: strings.TrimSpace(language[0]), Quality: quality}, nil
+	return &Lang{Name: name, Quality: quality}, nil
 }
 
-// findPreferredLanguages returns a list of languages that are available.
-func findPreferredLanguages(parsedLanguages []Lang, available []string) []Lang {
-	availableSet := make(map[string]struct{}, len(available))
-	for _, lang := range available {
-		availableSet[lang] = struct{}{}
+// languageMatch is an offered language along with how closely the language
+// range that matched it fits.
+type languageMatch struct {
+	Lang
+	distance int
+	order    int
+}
+
+// findPreferredLanguages returns a list of languages that are offered, sorted
+// by priority. Each language gets the quality of the closest language range
+// that matches it under the scheme, multiplied by the offer's source quality.
+// Among equally close ranges a refusal with a q-value of 0 wins, then the
+// highest quality. Languages are sorted by quality, then by how closely their
+// range matches, then by the position of the range in the header, so the
+// languages only matched by "*" keep the order of the offers.
+func findPreferredLanguages(parsedLanguages []Lang, offers []Offer, scheme LanguageScheme) []Lang {
+	matches := make([]languageMatch, 0)
+	for i, offer := range offers {
+		match := languageMatch{Lang: Lang{Name: offer.Value, Quality: -1}, distance: -1, order: i}
+		for _, lang := range parsedLanguages {
+			distance := languageDistance(scheme, lang.Name, offer.Value)
+			if distance < 0 {
+				continue
+			}
+
+			switch {
+			case match.distance >= 0 && distance > match.distance:
+				continue
+			case distance == match.distance && (match.Quality <= 0 || (lang.Quality > 0 && lang.Quality <= match.Quality)):
+				// An equally close range only wins if it refuses the language
+				// or has a higher quality.
+				continue
+			}
+
+			match.Quality = lang.Quality
+			match.Range = lang.Name
+			match.Index = lang.Index
+			match.distance = distance
+		}
+
+		if match.Quality > 0 {
+			match.Quality *= offer.sourceQuality()
+		}
+		if match.Quality > 0 {
+			matches = append(matches, match)
+		}
 	}
 
-	preferredLanguages := make([]Lang, 0)
-	for _, lang := range parsedLanguages {
-		if _, ok := availableSet[lang.Name]; ok {
-			preferredLanguages = append(preferredLanguages, lang)
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Quality != matches[j].Quality {
+			return matches[i].Quality > matches[j].Quality
 		}
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		if matches[i].Index != matches[j].Index {
+			return matches[i].Index < matches[j].Index
+		}
+		return matches[i].order < matches[j].order
+	})
+
+	preferredLanguages := make([]Lang, len(matches))
+	for i, match := range matches {
+		preferredLanguages[i] = match.Lang
 	}
 
 	return preferredLanguages
 }
 
-// sortLanguagesByPriority sorts a list of languages by priority.
+// sortLanguagesByPriority sorts a list of languages by priority, keeping the
+// order of the Accept-Language header for languages of equal quality.
 func sortLanguagesByPriority(languages []Lang) {
-	sort.Slice(languages, func(i, j int) bool {
-		return languages[i].Quality > languages[j].Quality
+	sort.SliceStable(languages, func(i, j int) bool {
+		if languages[i].Quality != languages[j].Quality {
+			return languages[i].Quality > languages[j].Quality
+		}
+		return languages[i].Index < languages[j].Index
 	})
 }
 
@@ -118,6 +118,42 @@ func TestNegotiator_ParseLanguage(t *testing.T) {
 			[]string{"fr", "de", "en", "it", "es", "pt", "no", "se", "fi", "ro", "nl"},
 			nil,
 		},
+		{
+			"should rank unlisted languages by the wildcard",
+			"fr-CH, fr;q=0.9, *;q=0.5",
+			[]string{"fr", "en", "de"},
+			[]string{"en", "fr", "de"},
+		},
+		{
+			"should rank the wildcard by its quality",
+			"*;q=0.9, en;q=0.5",
+			[]string{"de", "es", "en"},
+			[]string{"de", "en", "es"},
+		},
+		{
+			"should only return listed languages when the wildcard is refused",
+			"fr, en;q=0.5, *;q=0",
+			[]string{"fr", "en"},
+			[]string{"de", "en", "fr"},
+		},
+		{
+			"should keep a listed refusal over the wildcard",
+			"*, en;q=0",
+			[]string{"de", "fr"},
+			[]string{"de", "en", "fr"},
+		},
+		{
+			"should keep a refused language refused",
+			"en;q=0, es, en;q=0.5",
+			[]string{"es"},
+			[]string{"en", "es"},
+		},
+		{
+			"should exclude a language refused with spaces around the parameter",
+			"en ; q=0, *",
+			[]string{"fr"},
+			[]string{"en", "fr"},
+		},
 	}
 
 	for _, test := range tests {
@@ -150,3 +186,149 @@ func TestNegotiator_ParseLanguage(t *testing.T) {
 		})
 	}
 }
+
+func TestNegotiator_LanguageMatches(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "fr;q=0.5, en, de;q=0.8")
+
+	matches, err := negotiator.New(req).LanguageMatches("de", "fr", "es")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []negotiator.Lang{
+		{Name: "de", Quality: 0.8, Range: "de", Index: 2},
+		{Name: "fr", Quality: 0.5, Range: "fr", Index: 0},
+	}
+	if len(matches) != len(expected) {
+		t.Fatalf("Expected %v Languages , got %v", expected, matches)
+	}
+	for i, v := range matches {
+		if v != expected[i] {
+			t.Errorf("Expected %v Language , got %v", expected[i], v)
+		}
+	}
+}
+
+func TestNegotiator_NegotiateLanguages(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "en, de;q=0.9")
+
+	matches, err := negotiator.New(req).NegotiateLanguages(
+		negotiator.Offer{Value: "en", Quality: 0.5},
+		negotiator.Offer{Value: "de", Quality: 1},
+	)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(matches) != 2 || matches[0].Name != "de" || matches[1].Name != "en" {
+		t.Errorf("Expected de, en Languages , got %v", matches)
+	}
+	if matches[1].Quality != 0.5 {
+		t.Errorf("Expected en with q=0.5, got q=%v", matches[1].Quality)
+	}
+}
+
+func TestNegotiator_MatchLanguages(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		scheme         negotiator.LanguageScheme
+		acceptLanguage string
+		expected       []string
+		available      []string
+	}{
+		{
+			"should match exactly, ignoring case",
+			negotiator.ExactMatch,
+			"en-us, en",
+			[]string{"en-US"},
+			[]string{"en-GB", "en-US"},
+		},
+		{
+			"should match a prefix with basic filtering",
+			negotiator.BasicFiltering,
+			"en",
+			[]string{"en-GB", "en-US"},
+			[]string{"en-GB", "de", "en-US"},
+		},
+		{
+			"should rank closer matches first with basic filtering",
+			negotiator.BasicFiltering,
+			"en, fr;q=0.5",
+			[]string{"en", "en-US", "fr-CA"},
+			[]string{"fr-CA", "en-US", "en"},
+		},
+		{
+			"should not match a longer range with basic filtering",
+			negotiator.BasicFiltering,
+			"en-US",
+			[]string{},
+			[]string{"en"},
+		},
+		{
+			"should let a closer refusal win with basic filtering",
+			negotiator.BasicFiltering,
+			"en, en-GB;q=0",
+			[]string{"en-US"},
+			[]string{"en-GB", "en-US"},
+		},
+		{
+			"should rank wildcard matches last with basic filtering",
+			negotiator.BasicFiltering,
+			"*;q=0.5, en;q=0.5",
+			[]string{"en-US", "de", "fr"},
+			[]string{"de", "en-US", "fr"},
+		},
+		{
+			"should match wildcard subtags with extended filtering",
+			negotiator.ExtendedFiltering,
+			"de-*-DE",
+			[]string{"de-DE", "de-Latn-DE", "de-Latf-DE", "de-DE-x-goethe", "de-Latn-DE-1996"},
+			[]string{"de-DE", "de-Latn-DE", "de-Latf-DE", "de-DE-x-goethe", "de-Latn-DE-1996", "de", "de-x-DE", "de-Deva"},
+		},
+		{
+			"should match a prefix with extended filtering",
+			negotiator.ExtendedFiltering,
+			"de-DE",
+			[]string{"de-DE", "de-Latn-DE"},
+			[]string{"de-Latn-DE", "de-DE", "de-AT"},
+		},
+		{
+			"should fall back to shorter tags with lookup",
+			negotiator.Lookup,
+			"zh-Hant-TW",
+			[]string{"zh-Hant", "zh"},
+			[]string{"zh", "zh-Hans", "zh-Hant"},
+		},
+		{
+			"should rank by quality before closeness with lookup",
+			negotiator.Lookup,
+			"zh-Hant-TW, en;q=0.5, fr-CA-x-private;q=0.8",
+			[]string{"zh", "fr", "en"},
+			[]string{"en", "fr", "zh", "en-US"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Language", test.acceptLanguage)
+
+			matches, err := negotiator.New(req).MatchLanguages(test.scheme, test.available...)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if len(matches) != len(test.expected) {
+				t.Fatalf("Expected %s Languages , got %v", test.expected, matches)
+			}
+			for i, v := range matches {
+				if v.Name != test.expected[i] {
+					t.Errorf("Expected %s Language , got %s", test.expected[i], v.Name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package negotiator
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// LocaleSource is a place a LocaleResolver looks for the locale of a request.
+type LocaleSource int
+
+const (
+	// LocaleQuery is a query parameter, such as ?lang=fr.
+	LocaleQuery LocaleSource = iota
+	// LocalePath is the first segment of the URL path, such as /fr/about.
+	LocalePath
+	// LocaleCookie is a cookie holding a previously chosen locale.
+	LocaleCookie
+	// LocaleHeader is the Accept-Language header.
+	LocaleHeader
+	// LocaleDefault means no source had an available locale.
+	LocaleDefault
+)
+
+// Locale is the locale a LocaleResolver chose for a request.
+type Locale struct {
+	// Language is the chosen entry of LocaleResolver.Available.
+	Language string
+	// Source is where the locale was found.
+	Source LocaleSource
+}
+
+// LocaleResolver chooses the locale of a request, letting an explicit choice
+// in the query, the URL path or a cookie override the Accept-Language header.
+type LocaleResolver struct {
+	// Available lists the locales the application supports. The first one is
+	// used when no source has an available locale.
+	Available []string
+	// Order lists the sources to check, in order. It defaults to the query,
+	// the path, the cookie and then the header.
+	Order []LocaleSource
+	// QueryParam is the name of the query parameter. It defaults to "lang".
+	QueryParam string
+	// CookieName is the name of the cookie. It defaults to "lang".
+	CookieName string
+	// Persist writes the chosen locale to the cookie when it came from the
+	// query or the path.
+	Persist bool
+	// StripPath removes the locale prefix from the URL path of the request
+	// passed on by Handler.
+	StripPath bool
+	// Options configure the Negotiator used for the Accept-Language header,
+	// for example with WithLanguageFallback.
+	Options []Option
+}
+
+type localeContextKey struct{}
+
+// LocaleFromContext returns the locale stored by LocaleResolver.Handler.
+func LocaleFromContext(ctx context.Context) (Locale, bool) {
+	locale, ok := ctx.Value(localeContextKey{}).(Locale)
+	return locale, ok
+}
+
+// Handler returns middleware that resolves the locale of every request and
+// stores it in the request context, see LocaleFromContext.
+func (lr *LocaleResolver) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale := lr.Resolve(w, r)
+
+		if lr.StripPath && locale.Source == LocalePath {
+			r = stripLocalePrefix(r)
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), localeContextKey{}, locale)))
+	})
+}
+
+// Resolve chooses the locale of a request. Every candidate is checked against
+// the available locales, ignoring case and deprecated forms, and the available
+// form is returned. When Persist is set and w is not nil, a locale from the
+// query or the path is written to the cookie.
+func (lr *LocaleResolver) Resolve(w http.ResponseWriter, r *http.Request) Locale {
+	order := lr.Order
+	if order == nil {
+		order = []LocaleSource{LocaleQuery, LocalePath, LocaleCookie, LocaleHeader}
+	}
+
+	for _, source := range order {
+		language, ok := lr.lookup(source, r)
+		if !ok {
+			continue
+		}
+
+		if lr.Persist && w != nil && (source == LocaleQuery || source == LocalePath) {
+			http.SetCookie(w, &http.Cookie{
+				Name:     lr.cookieName(),
+				Value:    language,
+				Path:     "/",
+				MaxAge:   365 * 24 * 60 * 60,
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+
+		return Locale{Language: language, Source: source}
+	}
+
+	if len(lr.Available) == 0 {
+		return Locale{Source: LocaleDefault}
+	}
+
+	return Locale{Language: lr.Available[0], Source: LocaleDefault}
+}
+
+// lookup returns the available locale found in a source of the request.
+func (lr *LocaleResolver) lookup(source LocaleSource, r *http.Request) (string, bool) {
+	switch source {
+	case LocaleQuery:
+		queryParam := lr.QueryParam
+		if queryParam == "" {
+			queryParam = "lang"
+		}
+		return lr.available(r.URL.Query().Get(queryParam))
+	case LocalePath:
+		return lr.available(localePrefix(r.URL.Path))
+	case LocaleCookie:
+		cookie, err := r.Cookie(lr.cookieName())
+		if err != nil {
+			return "", false
+		}
+		return lr.available(cookie.Value)
+	case LocaleHeader:
+		if r.Header.Get("Accept-Language") == "" {
+			return "", false
+		}
+		result, err := New(r, lr.Options...).Language(lr.Available...)
+		if err != nil || result.Source == LanguageDefault {
+			return "", false
+		}
+		return lr.available(result.Name)
+	}
+
+	return "", false
+}
+
+// available returns the available locale equal to the candidate.
+func (lr *LocaleResolver) available(candidate string) (string, bool) {
+	if candidate == "" {
+		return "", false
+	}
+
+	for _, language := range lr.Available {
+		if canonicalLanguage(language) == canonicalLanguage(candidate) {
+			return language, true
+		}
+	}
+
+	return "", false
+}
+
+// cookieName returns the name of the cookie holding the locale.
+func (lr *LocaleResolver) cookieName() string {
+	if lr.CookieName == "" {
+		return "lang"
+	}
+
+	return lr.CookieName
+}
+
+// localePrefix returns the first segment of a URL path.
+func localePrefix(path string) string {
+	segment := strings.TrimPrefix(path, "/")
+	if i := strings.IndexByte(segment, '/'); i >= 0 {
+		segment = segment[:i]
+	}
+
+	return segment
+}
+
+// stripLocalePrefix returns a copy of the request without the first segment of
+// its URL path.
+func stripLocalePrefix(r *http.Request) *http.Request {
+	prefix := "/" + localePrefix(r.URL.Path)
+
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+	r2.URL.Path = strings.TrimPrefix(r.URL.Path, prefix)
+	r2.URL.RawPath = ""
+	if r2.URL.Path == "" {
+		r2.URL.Path = "/"
+	}
+
+	return r2
+}
@@ -0,0 +1,76 @@
+package negotiator_test
+
+import (
+	"github.com/noelukwa/negotiator"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLocaleResolver_Resolve(t *testing.T) {
+
+	resolver := &negotiator.LocaleResolver{Available: []string{"en", "fr", "pt-BR"}}
+
+	tests := []struct {
+		name           string
+		target         string
+		cookie         string
+		acceptLanguage string
+		expected       string
+		source         negotiator.LocaleSource
+	}{
+		{"should prefer the query parameter", "/fr/about?lang=pt-br", "en", "fr", "pt-BR", negotiator.LocaleQuery},
+		{"should use the path prefix", "/fr/about", "en", "pt-BR", "fr", negotiator.LocalePath},
+		{"should use the cookie", "/about", "pt-BR", "fr", "pt-BR", negotiator.LocaleCookie},
+		{"should use the header", "/about", "", "de, fr;q=0.5", "fr", negotiator.LocaleHeader},
+		{"should skip unavailable candidates", "/de/about?lang=es", "it", "fr", "fr", negotiator.LocaleHeader},
+		{"should fall back to the first available locale", "/about", "", "de", "en", negotiator.LocaleDefault},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, test.target, nil)
+			req.Header.Set("Accept-Language", test.acceptLanguage)
+			if test.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "lang", Value: test.cookie})
+			}
+
+			locale := resolver.Resolve(nil, req)
+			if locale.Language != test.expected || locale.Source != test.source {
+				t.Errorf("Expected %s locale from %d, got %s from %d", test.expected, test.source, locale.Language, locale.Source)
+			}
+		})
+	}
+}
+
+func TestLocaleResolver_Handler(t *testing.T) {
+	resolver := &negotiator.LocaleResolver{
+		Available: []string{"en", "fr"},
+		Order:     []negotiator.LocaleSource{negotiator.LocalePath, negotiator.LocaleHeader},
+		Persist:   true,
+		StripPath: true,
+	}
+
+	var locale negotiator.Locale
+	var path string
+	handler := resolver.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale, _ = negotiator.LocaleFromContext(r.Context())
+		path = r.URL.Path
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/FR/about", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if locale.Language != "fr" || locale.Source != negotiator.LocalePath {
+		t.Errorf("Expected fr locale from the path, got %s from %d", locale.Language, locale.Source)
+	}
+	if path != "/about" {
+		t.Errorf("Expected /about path, got %s", path)
+	}
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "lang" || cookies[0].Value != "fr" {
+		t.Errorf("Expected lang=fr cookie, got %v", cookies)
+	}
+}
@@ -6,15 +6,31 @@ import (
 	"strings"
 )
 
+// MediaType represents a media type, either a media range from the Accept
+// header or an offer matched against it.
 type MediaType struct {
 	Type       string
 	Subtype    string
 	Quality    float64
 	Parameters map[string]string
+	// Extensions holds the accept-extension parameters that follow the q
+	// parameter of a media range, or of the range that matched an offer.
+	// They never take part in matching.
+	Extensions map[string]string
+	// Value is the media type as it was written in the header or the offer.
+	Value string
+	// Range is the client media range that matched the offer.
+	Range string
+	// Index is the position of the media range in the Accept header.
+	Index int
 }
 
 // ParseMediaTypes parses the Accept header and returns a list of media types
-// accepted by the client, sorted by priority.
+// accepted by the client, sorted by priority. With available media types, only
+// the client media ranges that match one of them are returned, matched as in
+// NegotiateMediaTypes, so WithSuffixMatching applies. An available media type
+// refused by its most specific range, as text/plain is by
+// "text/*, text/plain;q=0", keeps the other ranges from being returned for it.
 func (n *Negotiator) ParseMediaTypes(available ...string) []string {
 	accept := n.req.Header.Get("Accept")
 
@@ -25,8 +41,23 @@ func (n *Negotiator) ParseMediaTypes(available ...string) []string {
 	parsedMediaTypes := splitMediaTypes(accept)
 	preferredMediaTypes := make([]MediaType, 0)
 
+	// Offers whose most specific matching range has a q-value of 0 are
+	// refused, and no other range may return them.
+	acceptable := make([]string, 0, len(available))
+	for _, a := range available {
+		if offer := parseMediaType(a); offer != nil {
+			if mediaRange, specificity := n.bestMediaRange(parsedMediaTypes, *offer); specificity >= 0 && mediaRange.Quality <= 0 {
+				continue
+			}
+		}
+		acceptable = append(acceptable, a)
+	}
+	if len(available) > 0 && len(acceptable) == 0 {
+		return []string{}
+	}
+
 	for _, mediaType := range parsedMediaTypes {
-		if isMediaTypeAccepted(mediaType, available) {
+		if mediaType.Quality > 0 && n.isMediaTypeAccepted(mediaType, acceptable) {
 			preferredMediaTypes = append(preferredMediaTypes, mediaType)
 		}
 	}
@@ -36,15 +67,181 @@ func (n *Negotiator) ParseMediaTypes(available ...string) []string {
 	return getMediaTypes(preferredMediaTypes)
 }
 
+// MediaType returns the offer the client prefers most, or an empty string
+// when none of the offers is acceptable.
+func (n *Negotiator) MediaType(offers ...string) string {
+	mediaTypes := n.MediaTypes(offers...)
+	if len(mediaTypes) == 0 {
+		return ""
+	}
+
+	return mediaTypes[0]
+}
+
+// MediaTypes returns the offers that are acceptable to the client, sorted by
+// the client's preference. Unlike ParseMediaTypes, the returned values are
+// taken from the offers rather than from the Accept header, so they can be
+// written straight into a Content-Type header.
+func (n *Negotiator) MediaTypes(offers ...string) []string {
+	matches := n.MediaTypeMatches(offers...)
+
+	result := make([]string, len(matches))
+	for i, match := range matches {
+		result[i] = match.Value
+	}
+
+	return result
+}
+
+// MediaTypeMatches returns the offers that are acceptable to the client,
+// sorted by the client's preference. Each result carries the parameters of the
+// offer, the client media range that matched it, its position in the Accept
+// header and the effective quality.
+func (n *Negotiator) MediaTypeMatches(offers ...string) []MediaType {
+	return n.NegotiateMediaTypes(Offers(offers...)...)
+}
+
+// NegotiateMediaTypes is like MediaTypeMatches, but weighs each offer by its
+// source quality, so that for Accept: */* the offer with the highest source
+// quality comes first.
+func (n *Negotiator) NegotiateMediaTypes(offers ...Offer) []MediaType {
+	accept := n.req.Header.Get("Accept")
+
+	if accept == "" {
+		accept = "*/*"
+	}
+
+	accepted := n.rankOffers(splitMediaTypes(accept), offers)
+
+	result := make([]MediaType, len(accepted))
+	for i, offer := range accepted {
+		result[i] = offer.MediaType
+	}
+
+	return result
+}
+
+// AcceptedMediaTypes is like NegotiateMediaTypes, but with WithStrictParsing
+// it returns a *ParseError when the Accept header is malformed.
+func (n *Negotiator) AcceptedMediaTypes(offers ...Offer) ([]MediaType, error) {
+	if err := n.checkStrict("Accept"); err != nil {
+		return nil, err
+	}
+
+	return n.NegotiateMediaTypes(offers...), nil
+}
+
+// mediaOffer is an offer along with the client media range that matched it.
+type mediaOffer struct {
+	MediaType
+	specificity int
+	order       int
+}
+
+// rankOffers assigns every offer the quality of the most specific client media
+// range that matches it, as described in RFC 9110 section 12.5.1, so a range
+// with a q-value of 0 excludes the offers it covers even when a broader range
+// accepts them. It returns the acceptable offers sorted by quality, then by the
+// specificity of the matching range, then by its position in the header, then
+// by the order of the offers. The quality of an offer is the q-value of its
+// matching range multiplied by the offer's source quality.
+func (n *Negotiator) rankOffers(mediaRanges []MediaType, offers []Offer) []mediaOffer {
+	accepted := make([]mediaOffer, 0, len(offers))
+
+	for i, offer := range offers {
+		offerMediaType := parseMediaType(offer.Value)
+		if offerMediaType == nil {
+			continue
+		}
+
+		match := mediaOffer{MediaType: *offerMediaType, order: i}
+		mediaRange, specificity := n.bestMediaRange(mediaRanges, *offerMediaType)
+		if specificity >= 0 {
+			match.Quality = mediaRange.Quality * offer.sourceQuality()
+			match.Range = mediaRange.Value
+			match.Extensions = mediaRange.Extensions
+			match.Index = mediaRange.Index
+		}
+		match.specificity = specificity
+
+		if match.specificity >= 0 && match.Quality > 0 {
+			accepted = append(accepted, match)
+		}
+	}
+
+	sort.SliceStable(accepted, func(i, j int) bool {
+		if accepted[i].Quality != accepted[j].Quality {
+			return accepted[i].Quality > accepted[j].Quality
+		}
+		if accepted[i].specificity != accepted[j].specificity {
+			return accepted[i].specificity > accepted[j].specificity
+		}
+		if accepted[i].Index != accepted[j].Index {
+			return accepted[i].Index < accepted[j].Index
+		}
+		return accepted[i].order < accepted[j].order
+	})
+
+	return accepted
+}
+
+// bestMediaRange returns the most specific client media range that matches
+// the offer along with its specificity, or a specificity of -1 when none
+// does. Ranges of equal specificity keep the one listed first.
+func (n *Negotiator) bestMediaRange(mediaRanges []MediaType, offer MediaType) (MediaType, int) {
+	best, bestSpecificity := MediaType{}, -1
+	for _, mediaRange := range mediaRanges {
+		if specificity := n.matchMediaRange(mediaRange, offer); specificity > bestSpecificity {
+			best, bestSpecificity = mediaRange, specificity
+		}
+	}
+
+	return best, bestSpecificity
+}
+
+// matchMediaRange scores how specifically a client media range matches an
+// offer, or returns -1 when it does not match at all.
+func (n *Negotiator) matchMediaRange(mediaRange MediaType, offer MediaType) int {
+	if rangeMatchesOffer(mediaRange, offer) {
+		return mediaRangeSpecificity(mediaRange)
+	}
+
+	if n.suffixMatching && rangeMatchesSuffix(mediaRange, offer) {
+		// A suffix match sits between a concrete subtype and a subtype wildcard.
+		return mediaRangeSpecificity(mediaRange) - 1<<8 + 1<<7
+	}
+
+	return -1
+}
+
+// mediaRangeSpecificity scores how specific a media range is. A concrete type
+// outranks a type wildcard, a concrete subtype outranks a subtype wildcard, and
+// among otherwise equal ranges the one with more parameters wins.
+func mediaRangeSpecificity(mediaRange MediaType) int {
+	specificity := 0
+
+	if mediaRange.Type != "*" {
+		specificity += 1 << 16
+	}
+
+	if mediaRange.Subtype != "*" {
+		specificity += 1 << 8
+	}
+
+	return specificity + len(mediaRange.Parameters)
+}
+
 // splitMediaTypes splits the Accept header into individual media types with quality values.
+// Media types with a q-value of 0 are kept, as they exclude the offers they match.
 func splitMediaTypes(accept string) []MediaType {
-	mediaTypes := strings.Split(accept, ",")
+	mediaTypes := splitUnquoted(accept, ',')
 
 	parsedMediaTypes := make([]MediaType, 0)
 
-	for _, mediaTypeStr := range mediaTypes {
+	for i, mediaTypeStr := range mediaTypes {
 		mediaType := parseMediaType(mediaTypeStr)
-		if mediaType != nil && mediaType.Quality > 0 {
+		if mediaType != nil {
+			mediaType.Index = i
 			parsedMediaTypes = append(parsedMediaTypes, *mediaType)
 		}
 	}
@@ -53,6 +250,9 @@ func splitMediaTypes(accept string) []MediaType {
 }
 
 // parseMediaType parses a media type string into a MediaType struct.
+// Following the grammar of RFC 9110 section 12.5.1, the parameters before q
+// are media type parameters, q is the weight, and the parameters after q are
+// accept-extensions. Parameter names are case-insensitive and are lowercased.
 func parseMediaType(mediaTypeStr string) *MediaType {
 	mediaTypeParts := strings.SplitN(strings.TrimSpace(mediaTypeStr), ";", 2)
 	if len(mediaTypeParts) == 0 {
@@ -61,21 +261,27 @@ func parseMediaType(mediaTypeStr string) *MediaType {
 
 	mediaRange := strings.TrimSpace(mediaTypeParts[0])
 	mediaTypeParams := make(map[string]string)
+	extensions := make(map[string]string)
+	qValue := 1.0
 
 	if len(mediaTypeParts) > 1 {
-		parameters := splitParameters(mediaTypeParts[1])
-		for _, param := range parameters {
+		params, weighted := mediaTypeParams, false
+		for _, param := range splitParameters(mediaTypeParts[1]) {
 			key, val := splitKeyValuePair(param)
-			mediaTypeParams[key] = val
-		}
-	}
-
-	qValue := 1.0
-
-	if qValueStr, ok := mediaTypeParams["q"]; ok {
-		q, err := strconv.ParseFloat(qValueStr, 64)
-		if err == nil {
-			qValue = q
+			key = strings.ToLower(key)
+			if key == "" {
+				continue
+			}
+
+			if key == "q" && !weighted {
+				if q, err := strconv.ParseFloat(val, 64); err == nil {
+					qValue = q
+				}
+				params, weighted = extensions, true
+				continue
+			}
+
+			params[key] = val
 		}
 	}
 
@@ -89,11 +295,16 @@ func parseMediaType(mediaTypeStr string) *MediaType {
 		Subtype:    strings.TrimSpace(mediaRangeParts[1]),
 		Quality:    qValue,
 		Parameters: mediaTypeParams,
+		Extensions: extensions,
+		Value:      strings.TrimSpace(mediaTypeStr),
 	}
 }
 
-// isMediaTypeAccepted checks if a media type is accepted by the client.
-func isMediaTypeAccepted(mediaType MediaType, available []string) bool {
+// isMediaTypeAccepted checks if a client media range is kept for the available
+// media types: either it matches one of them through matchMediaRange, which
+// honors WithSuffixMatching, or an available media type used as a pattern
+// covers it.
+func (n *Negotiator) isMediaTypeAccepted(mediaType MediaType, available []string) bool {
 	if len(available) == 0 {
 		return true
 	}
@@ -102,6 +313,10 @@ func isMediaTypeAccepted(mediaType MediaType, available []string) bool {
 		if matchMediaType(mediaType, a) {
 			return true
 		}
+
+		if availableMediaType := parseMediaType(a); availableMediaType != nil && n.matchMediaRange(mediaType, *availableMediaType) >= 0 {
+			return true
+		}
 	}
 
 	return false
@@ -131,10 +346,77 @@ func matchMediaType(mediaType MediaType, available string) bool {
 	return true
 }
 
-// sortMediaTypesByPriority sorts the media types by their priority (q-values).
+// rangeMatchesOffer checks if a client media range covers a specific offer.
+// Every parameter of the range must be present on the offer, except for the
+// profile parameter, where every profile the range asks for must be listed by
+// the offer, and the charset parameter, which is case-insensitive.
+func rangeMatchesOffer(mediaRange MediaType, offer MediaType) bool {
+	if mediaRange.Type != "*" && !strings.EqualFold(mediaRange.Type, offer.Type) {
+		return false
+	}
+
+	if mediaRange.Subtype != "*" && !strings.EqualFold(mediaRange.Subtype, offer.Subtype) {
+		return false
+	}
+
+	for key, val := range mediaRange.Parameters {
+		switch key {
+		case "profile":
+			if !profilesInclude(offer.Parameters[key], val) {
+				return false
+			}
+		case "charset":
+			if !strings.EqualFold(offer.Parameters[key], val) {
+				return false
+			}
+		default:
+			if offer.Parameters[key] != val {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// rangeMatchesSuffix checks if a client media range covers an offer through
+// the offer's structured syntax suffix (RFC 6839). The range either names the
+// base type of the suffix, as application/json does for +json, or is a suffix
+// wildcard such as application/*+json.
+func rangeMatchesSuffix(mediaRange MediaType, offer MediaType) bool {
+	suffix := subtypeSuffix(offer.Subtype)
+	if suffix == "" {
+		return false
+	}
+
+	if !strings.EqualFold(mediaRange.Subtype, suffix) && !strings.EqualFold(mediaRange.Subtype, "*+"+suffix) {
+		return false
+	}
+
+	offerBase := MediaType{Type: offer.Type, Subtype: mediaRange.Subtype, Parameters: offer.Parameters}
+
+	return rangeMatchesOffer(mediaRange, offerBase)
+}
+
+// subtypeSuffix returns the structured syntax suffix of a subtype, such as
+// json for vnd.acme+json, or an empty string when it has none.
+func subtypeSuffix(subtype string) string {
+	i := strings.LastIndex(subtype, "+")
+	if i < 0 {
+		return ""
+	}
+
+	return subtype[i+1:]
+}
+
+// sortMediaTypesByPriority sorts the media types by their priority (q-values),
+// preferring more specific media ranges when the q-values are equal.
 func sortMediaTypesByPriority(mediaTypes []MediaType) {
 	sort.SliceStable(mediaTypes, func(i, j int) bool {
-		return mediaTypes[i].Quality > mediaTypes[j].Quality
+		if mediaTypes[i].Quality != mediaTypes[j].Quality {
+			return mediaTypes[i].Quality > mediaTypes[j].Quality
+		}
+		return mediaRangeSpecificity(mediaTypes[i]) > mediaRangeSpecificity(mediaTypes[j])
 	})
 }
 
@@ -150,10 +432,11 @@ func getMediaTypes(mediaTypes []MediaType) []string {
 }
 
 // splitParameters splits a string of parameters into individual parameter strings.
+// Semicolons inside quoted strings do not separate parameters.
 func splitParameters(paramsStr string) []string {
 	parameters := make([]string, 0)
 
-	paramParts := strings.Split(paramsStr, ";")
+	paramParts := splitUnquoted(paramsStr, ';')
 
 	for _, param := range paramParts {
 		parameters = append(parameters, strings.TrimSpace(param))
@@ -162,6 +445,31 @@ func splitParameters(paramsStr string) []string {
 	return parameters
 }
 
+// splitUnquoted splits a string around each separator that is not inside a
+// quoted string or, as profile URIs may be written, inside angle brackets.
+func splitUnquoted(str string, sep byte) []string {
+	parts := make([]string, 0)
+
+	start, quoted, bracketed := 0, false, false
+	for i := 0; i < len(str); i++ {
+		switch {
+		case str[i] == '\\' && quoted:
+			i++
+		case str[i] == '"' && !bracketed:
+			quoted = !quoted
+		case str[i] == '<' && !quoted:
+			bracketed = true
+		case str[i] == '>' && !quoted:
+			bracketed = false
+		case str[i] == sep && !quoted && !bracketed:
+			parts = append(parts, str[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, str[start:])
+}
+
 // splitKeyValuePair splits a key-value pair string into key and value strings.
 func splitKeyValuePair(pairStr string) (string, string) {
 	pairParts := strings.SplitN(pairStr, "=", 2)
@@ -35,6 +35,18 @@ func TestNegotiator_ParseMediaTypes(t *testing.T) {
 				"text/*",
 			},
 		},
+		{
+			name:      "should not return text/* for a refused text/plain",
+			header:    "text/*, text/plain;q=0",
+			available: []string{"text/plain"},
+			expected:  []string{},
+		},
+		{
+			name:      "should return text/* for other text offers",
+			header:    "text/*, text/plain;q=0",
+			available: []string{"text/plain", "text/html"},
+			expected:  []string{"text/*"},
+		},
 		{
 			name:   "should return application/json",
 			header: "application/json",
@@ -105,7 +117,7 @@ func TestNegotiator_ParseMediaTypes(t *testing.T) {
 			req.Header.Set("Accept", c.header)
 
 			neg := negotiator.New(req)
-			actual := neg.ParseMediaTypes()
+			actual := neg.ParseMediaTypes(c.available...)
 			if len(c.expected) >= 1 {
 				for i, v := range actual {
 					if v != c.expected[i] {
@@ -122,3 +134,421 @@ func TestNegotiator_ParseMediaTypes(t *testing.T) {
 	}
 
 }
+
+func TestNegotiator_MediaTypes(t *testing.T) {
+
+	cases := []struct {
+		name     string
+		header   string
+		offers   []string
+		expected []string
+	}{
+		{
+			name:     "should return offers in server order when header is empty",
+			header:   "",
+			offers:   []string{"application/json", "text/html"},
+			expected: []string{"application/json", "text/html"},
+		},
+		{
+			name:     "should return the offer matched by a wildcard",
+			header:   "text/*",
+			offers:   []string{"application/json", "text/html"},
+			expected: []string{"text/html"},
+		},
+		{
+			name:     "should sort offers by client preference",
+			header:   "application/json;q=0.5, text/html",
+			offers:   []string{"application/json", "text/html"},
+			expected: []string{"text/html", "application/json"},
+		},
+		{
+			name:     "should match parameters on the range",
+			header:   "text/html;level=1",
+			offers:   []string{"text/html", "text/html;level=1"},
+			expected: []string{"text/html;level=1"},
+		},
+		{
+			name:     "should exclude offers refused with q=0",
+			header:   "text/*, text/plain;q=0",
+			offers:   []string{"text/plain", "text/html"},
+			expected: []string{"text/html"},
+		},
+		{
+			name:     "should exclude offers refused with q=0 under */*",
+			header:   "*/*, application/xml;q=0",
+			offers:   []string{"application/xml", "application/json"},
+			expected: []string{"application/json"},
+		},
+		{
+			name:     "should return empty list when nothing matches",
+			header:   "image/png",
+			offers:   []string{"application/json", "text/html"},
+			expected: []string{},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Accept", c.header)
+
+			neg := negotiator.New(req)
+			actual := neg.MediaTypes(c.offers...)
+			if len(actual) != len(c.expected) {
+				t.Fatalf("Expected %s media types, got %s", c.expected, actual)
+			}
+			for i, v := range actual {
+				if v != c.expected[i] {
+					t.Errorf("Expected %s media type, got %s", c.expected[i], v)
+				}
+			}
+
+			best := neg.MediaType(c.offers...)
+			if len(c.expected) == 0 && best != "" {
+				t.Errorf("Expected no media type, got %s", best)
+			}
+			if len(c.expected) > 0 && best != c.expected[0] {
+				t.Errorf("Expected %s media type, got %s", c.expected[0], best)
+			}
+		})
+	}
+
+}
+
+func TestNegotiator_MediaTypesPrecedence(t *testing.T) {
+
+	// Examples from RFC 9110 section 12.5.1 and RFC 7231 section 5.3.2.
+	cases := []struct {
+		name   string
+		header string
+		rank   []string
+	}{
+		{
+			name:   "should prefer the most specific range (RFC 9110)",
+			header: "text/*, text/plain, text/plain;format=flowed, */*",
+			rank: []string{
+				"text/plain;format=flowed",
+				"text/plain",
+				"text/html",
+				"image/jpeg",
+			},
+		},
+		{
+			name:   "should assign each offer the quality of its most specific range (RFC 9110)",
+			header: "text/*;q=0.3, text/plain;q=0.7, text/plain;format=flowed, text/plain;format=fixed;q=0.4, */*;q=0.5",
+			rank: []string{
+				"text/plain;format=flowed",
+				"text/plain",
+				"image/jpeg",
+				"text/plain;format=fixed",
+				"text/html",
+			},
+		},
+		{
+			name:   "should assign each offer the quality of its most specific range (RFC 7231)",
+			header: "text/*;q=0.3, text/html;q=0.7, text/html;level=1, text/html;level=2;q=0.4, */*;q=0.5",
+			rank: []string{
+				"text/html;level=1",
+				"text/html",
+				"image/jpeg",
+				"text/html;level=2",
+				"text/plain",
+			},
+		},
+		{
+			name:   "should break ties on specificity",
+			header: "*/*, text/*, text/html",
+			rank: []string{
+				"text/html",
+				"text/plain",
+				"image/png",
+			},
+		},
+		{
+			name:   "should break ties on header order",
+			header: "image/png, text/html",
+			rank: []string{
+				"image/png",
+				"text/html",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Accept", c.header)
+
+			// Offer the expected ranking in reverse so the server order
+			// cannot produce it by accident.
+			offers := make([]string, len(c.rank))
+			for i, v := range c.rank {
+				offers[len(c.rank)-1-i] = v
+			}
+
+			neg := negotiator.New(req)
+			actual := neg.MediaTypes(offers...)
+			if len(actual) != len(c.rank) {
+				t.Fatalf("Expected %s media types, got %s", c.rank, actual)
+			}
+			for i, v := range actual {
+				if v != c.rank[i] {
+					t.Errorf("Expected %s media type, got %s", c.rank[i], v)
+				}
+			}
+		})
+	}
+
+	t.Run("should order ranges by specificity", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", "*/*, text/*, text/plain, text/plain;format=flowed")
+
+		expected := []string{"text/plain", "text/plain", "text/*", "*/*"}
+		actual := negotiator.New(req).ParseMediaTypes()
+		if len(actual) != len(expected) {
+			t.Fatalf("Expected %s media types, got %s", expected, actual)
+		}
+		for i, v := range actual {
+			if v != expected[i] {
+				t.Errorf("Expected %s media type, got %s", expected[i], v)
+			}
+		}
+	})
+
+}
+
+func TestNegotiator_MediaTypeMatches(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "application/json;q=0.5, text/*;q=0.8")
+
+	matches := negotiator.New(req).MediaTypeMatches("application/json", "text/html;charset=utf-8")
+	if len(matches) != 2 {
+		t.Fatalf("Expected 2 media types, got %d", len(matches))
+	}
+
+	html := matches[0]
+	if html.Value != "text/html;charset=utf-8" || html.Type != "text" || html.Subtype != "html" {
+		t.Errorf("Expected text/html;charset=utf-8 media type, got %s", html.Value)
+	}
+	if html.Parameters["charset"] != "utf-8" {
+		t.Errorf("Expected utf-8 charset parameter, got %s", html.Parameters["charset"])
+	}
+	if html.Range != "text/*;q=0.8" || html.Index != 1 || html.Quality != 0.8 {
+		t.Errorf("Expected text/*;q=0.8 at 1 with q=0.8, got %s at %d with q=%v", html.Range, html.Index, html.Quality)
+	}
+
+	json := matches[1]
+	if json.Value != "application/json" || json.Range != "application/json;q=0.5" || json.Index != 0 || json.Quality != 0.5 {
+		t.Errorf("Expected application/json;q=0.5 at 0 with q=0.5, got %s at %d with q=%v", json.Range, json.Index, json.Quality)
+	}
+}
+
+func TestNegotiator_MediaTypesSuffix(t *testing.T) {
+
+	cases := []struct {
+		name     string
+		header   string
+		offers   []string
+		suffix   bool
+		expected []string
+	}{
+		{
+			name:     "should not match suffixes unless enabled",
+			header:   "application/json",
+			offers:   []string{"application/vnd.acme.order+json"},
+			expected: []string{},
+		},
+		{
+			name:     "should match the base type of a suffix",
+			header:   "application/json",
+			offers:   []string{"application/vnd.acme.order+json"},
+			suffix:   true,
+			expected: []string{"application/vnd.acme.order+json"},
+		},
+		{
+			name:     "should match a suffix wildcard",
+			header:   "application/*+json",
+			offers:   []string{"application/xml", "application/vnd.acme.order+json", "application/ld+json"},
+			suffix:   true,
+			expected: []string{"application/vnd.acme.order+json", "application/ld+json"},
+		},
+		{
+			name:     "should not match a different suffix",
+			header:   "application/*+xml, application/cbor",
+			offers:   []string{"application/vnd.acme.order+json"},
+			suffix:   true,
+			expected: []string{},
+		},
+		{
+			name:     "should rank suffix matches below exact matches",
+			header:   "application/json",
+			offers:   []string{"application/vnd.acme.order+json", "application/json"},
+			suffix:   true,
+			expected: []string{"application/json", "application/vnd.acme.order+json"},
+		},
+		{
+			name:     "should rank suffix matches above wildcards",
+			header:   "application/*;q=0.5, application/json;q=0.8",
+			offers:   []string{"application/xml", "application/vnd.acme.order+json"},
+			suffix:   true,
+			expected: []string{"application/vnd.acme.order+json", "application/xml"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Accept", c.header)
+
+			var opts []negotiator.Option
+			if c.suffix {
+				opts = append(opts, negotiator.WithSuffixMatching())
+			}
+
+			actual := negotiator.New(req, opts...).MediaTypes(c.offers...)
+			if len(actual) != len(c.expected) {
+				t.Fatalf("Expected %s media types, got %s", c.expected, actual)
+			}
+			for i, v := range actual {
+				if v != c.expected[i] {
+					t.Errorf("Expected %s media type, got %s", c.expected[i], v)
+				}
+			}
+		})
+	}
+
+}
+
+func TestNegotiator_MediaTypeExtensions(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", `text/html;Level=1;q=0.5;foo=bar;note="a;b", application/json;q=0.2`)
+
+	matches := negotiator.New(req).MediaTypeMatches("text/html;level=1", "application/json")
+	if len(matches) != 2 {
+		t.Fatalf("Expected 2 media types, got %d", len(matches))
+	}
+
+	html := matches[0]
+	if html.Value != "text/html;level=1" || html.Quality != 0.5 {
+		t.Errorf("Expected text/html;level=1 with q=0.5, got %s with q=%v", html.Value, html.Quality)
+	}
+	if _, ok := html.Parameters["q"]; ok {
+		t.Errorf("Expected q to be left out of the parameters, got %v", html.Parameters)
+	}
+	if len(html.Extensions) != 2 || html.Extensions["foo"] != "bar" || html.Extensions["note"] != "a;b" {
+		t.Errorf("Expected foo and note extensions, got %v", html.Extensions)
+	}
+
+	mediaTypes := negotiator.New(req).ParseMediaTypes()
+	if len(mediaTypes) != 2 || mediaTypes[0] != "text/html" {
+		t.Errorf("Expected text/html, application/json media types, got %s", mediaTypes)
+	}
+}
+
+func TestNegotiator_NegotiateMediaTypes(t *testing.T) {
+
+	cases := []struct {
+		name     string
+		header   string
+		offers   []negotiator.Offer
+		expected []string
+	}{
+		{
+			name:   "should prefer the offer with the higher source quality",
+			header: "*/*",
+			offers: []negotiator.Offer{
+				{Value: "application/xml", Quality: 0.8},
+				{Value: "application/json", Quality: 1},
+			},
+			expected: []string{"application/json", "application/xml"},
+		},
+		{
+			name:   "should never choose an offer with a source quality of 0",
+			header: "text/html, */*;q=0.1",
+			offers: []negotiator.Offer{
+				negotiator.NewOffer("text/html", 0),
+				negotiator.NewOffer("application/json", 1.5),
+			},
+			expected: []string{"application/json"},
+		},
+		{
+			name:   "should treat an unset source quality as 1",
+			header: "text/html, */*;q=0.1",
+			offers: []negotiator.Offer{
+				{Value: "application/json"},
+				{Value: "text/html"},
+			},
+			expected: []string{"text/html", "application/json"},
+		},
+		{
+			name:   "should multiply the source quality with the client quality",
+			header: "application/xml, application/json;q=0.5",
+			offers: []negotiator.Offer{
+				{Value: "application/json", Quality: 1},
+				{Value: "application/xml", Quality: 0.8},
+			},
+			expected: []string{"application/xml", "application/json"},
+		},
+		{
+			name:   "should let the source quality outweigh the client quality",
+			header: "image/jpeg, image/png;q=0.9",
+			offers: []negotiator.Offer{
+				{Value: "image/jpeg", Quality: 0.5},
+				{Value: "image/png", Quality: 1},
+			},
+			expected: []string{"image/png", "image/jpeg"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Accept", c.header)
+
+			actual := negotiator.New(req).NegotiateMediaTypes(c.offers...)
+			if len(actual) != len(c.expected) {
+				t.Fatalf("Expected %s media types, got %v", c.expected, actual)
+			}
+			for i, v := range actual {
+				if v.Value != c.expected[i] {
+					t.Errorf("Expected %s media type, got %s", c.expected[i], v.Value)
+				}
+			}
+		})
+	}
+
+}
+
+func TestNegotiator_ParseMediaTypes_Available(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "application/*+json, text/html;q=0.5, image/png")
+
+	tests := []struct {
+		name     string
+		opts     []negotiator.Option
+		expected []string
+	}{
+		{"should keep the ranges matching an offer", nil, []string{"text/html"}},
+		{"should honor suffix matching", []negotiator.Option{negotiator.WithSuffixMatching()}, []string{"application/*+json", "text/html"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			neg := negotiator.New(req, test.opts...)
+			actual := neg.ParseMediaTypes("application/vnd.api+json", "text/html")
+			if len(actual) != len(test.expected) {
+				t.Fatalf("Expected %v media types, got %v", test.expected, actual)
+			}
+			for i, v := range actual {
+				if v != test.expected[i] {
+					t.Errorf("Expected %s media type, got %s", test.expected[i], v)
+				}
+			}
+
+			offers := neg.MediaTypes("application/vnd.api+json", "text/html")
+			if len(offers) != len(actual) {
+				t.Errorf("Expected ParseMediaTypes and MediaTypes to agree, got %v and %v", actual, offers)
+			}
+		})
+	}
+}
@@ -1,11 +1,123 @@
 package negotiator
 
-import "net/http"
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
 
 type Negotiator struct {
-	req *http.Request
+	req            *http.Request
+	suffixMatching bool
+	strict         bool
+	fallback       FallbackPolicy
+	charsetOrder   bool
+	encoders       *EncoderRegistry
 }
 
-func New(req *http.Request) *Negotiator {
-	return &Negotiator{req: req}
+// Option configures a Negotiator.
+type Option func(*Negotiator)
+
+func New(req *http.Request, opts ...Option) *Negotiator {
+	n := &Negotiator{req: req}
+	for _, opt := range opts {
+		opt(n)
+	}
+
+	return n
+}
+
+// WithSuffixMatching enables RFC 6839 structured syntax suffix matching for
+// media types, so an offer such as application/vnd.acme+json satisfies the
+// application/json and application/*+json media ranges. Suffix matches rank
+// below exact matches.
+func WithSuffixMatching() Option {
+	return func(n *Negotiator) {
+		n.suffixMatching = true
+	}
+}
+
+// Offer is a value the server can produce, such as a media type, language,
+// charset or encoding, along with a server-side source quality (qs) in the
+// range 0 to 1. The source quality is multiplied with the client's q-value, so
+// the server can prefer one representation over another. A Quality left at
+// zero means unset, and counts as 1, so Offer{Value: "text/html"} is an
+// ordinary offer; an offer that must never be chosen is made with
+// NewOffer(value, 0).
+type Offer struct {
+	Value   string
+	Quality float64
+
+	// never is set by NewOffer for a source quality of 0, which the Quality
+	// field alone cannot tell apart from unset.
+	never bool
+}
+
+// NewOffer returns an offer with the given source quality, clamped to the
+// range 0 to 1. A source quality of 0 or less makes an offer that is never
+// chosen.
+func NewOffer(value string, quality float64) Offer {
+	quality = clampQuality(quality)
+	return Offer{Value: value, Quality: quality, never: quality == 0}
+}
+
+// Offers returns offers with a source quality of 1 for the given values.
+func Offers(values ...string) []Offer {
+	offers := make([]Offer, len(values))
+	for i, value := range values {
+		offers[i] = Offer{Value: value, Quality: 1}
+	}
+
+	return offers
+}
+
+// sourceQuality returns the source quality of the offer, clamped to the range
+// 0 to 1, with an unset quality counting as 1.
+func (o Offer) sourceQuality() float64 {
+	if o.never {
+		return 0
+	}
+	if o.Quality == 0 {
+		return 1
+	}
+
+	return clampQuality(o.Quality)
+}
+
+// clampQuality limits a quality to the range 0 to 1.
+func clampQuality(quality float64) float64 {
+	if quality < 0 || math.IsNaN(quality) {
+		return 0
+	}
+	if quality > 1 {
+		return 1
+	}
+
+	return quality
+}
+
+// splitQuality splits an element of an Accept-Encoding, Accept-Charset or
+// Accept-Language header into its value and its q-value, which defaults to 1.
+// Parameters are trimmed and the q parameter is matched ignoring case, so
+// "gzip ; Q=0" is gzip refused. An unparsable q-value is returned as an error
+// along with the default quality.
+func splitQuality(element string) (string, float64, error) {
+	parts := strings.Split(element, ";")
+	value := strings.TrimSpace(parts[0])
+
+	for _, param := range parts[1:] {
+		key, rawQuality := splitKeyValuePair(param)
+		if !strings.EqualFold(key, "q") {
+			continue
+		}
+
+		quality, err := strconv.ParseFloat(rawQuality, 64)
+		if err != nil {
+			return value, 1, err
+		}
+		return value, quality, nil
+	}
+
+	return value, 1, nil
 }
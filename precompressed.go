@@ -0,0 +1,183 @@
+package negotiator
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// FileVariant is a precompressed variant of static files, stored next to each
+// file with an extra extension, such as app.js.br for app.js.
+type FileVariant struct {
+	// Encoding is the content coding of the variant, such as br.
+	Encoding string
+	// Extension is appended to the name of the file, such as .br.
+	Extension string
+}
+
+// DefaultFileVariants lists the br, zstd and gzip variants, in order of
+// preference.
+var DefaultFileVariants = []FileVariant{
+	{Encoding: "br", Extension: ".br"},
+	{Encoding: "zstd", Extension: ".zst"},
+	{Encoding: "gzip", Extension: ".gz"},
+}
+
+// Precompressed is an http.Handler that serves static files, choosing with
+// ParseEncoding among precompressed variants of each file that exist. A
+// variant is served with the Content-Type of the original file, its own
+// Content-Encoding and ETag, and Vary: Accept-Encoding, through
+// http.ServeContent, so range and conditional requests keep working. When the
+// client accepts no variant or sends no Accept-Encoding header, the original
+// file is served, and when it refuses the original file as well, the response
+// is 406 Not Acceptable.
+//
+// Directories and missing files are handled by http.FileServer.
+type Precompressed struct {
+	// Root is the file system to serve files from.
+	Root http.FileSystem
+	// Variants lists the precompressed variants to look for, in order of
+	// preference. It defaults to DefaultFileVariants.
+	Variants []FileVariant
+}
+
+// PrecompressedFileServer returns a handler that serves the files of root,
+// along with their br, zstd and gzip variants.
+func PrecompressedFileServer(root http.FileSystem) http.Handler {
+	return &Precompressed{Root: root}
+}
+
+// PrecompressedFileServerFS is like PrecompressedFileServer, but serves the
+// files of an fs.FS.
+func PrecompressedFileServerFS(fsys fs.FS) http.Handler {
+	return &Precompressed{Root: http.FS(fsys)}
+}
+
+func (p *Precompressed) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Path
+	if !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+	name = path.Clean(name)
+
+	file, info, ok := p.open(name)
+	if !ok {
+		http.FileServer(p.Root).ServeHTTP(w, r)
+		return
+	}
+	defer file.Close()
+
+	contentType, err := p.contentType(name, file)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	variants := p.variants()
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	// The variants found are kept open until the response is written, so the
+	// one chosen is served from the file that was probed.
+	variantFiles := make(map[string]http.File, len(variants))
+	variantInfos := make(map[string]fs.FileInfo, len(variants))
+	defer func() {
+		for _, variantFile := range variantFiles {
+			variantFile.Close()
+		}
+	}()
+
+	available := make([]string, 0, len(variants)+1)
+	encoders := NewEncoderRegistry()
+	for i, variant := range variants {
+		if _, ok := variantFiles[variant.Encoding]; ok {
+			continue
+		}
+		if variantFile, variantInfo, ok := p.open(name + variant.Extension); ok {
+			variantFiles[variant.Encoding], variantInfos[variant.Encoding] = variantFile, variantInfo
+			available = append(available, variant.Encoding)
+			encoders.Register(Encoder{Name: variant.Encoding, Rank: len(variants) - i})
+		}
+	}
+	available = append(available, "identity")
+
+	// Without an Accept-Encoding header any coding is acceptable, but clients
+	// that send none rarely decode any.
+	encodings := []string{"identity"}
+	if r.Header.Get("Accept-Encoding") != "" {
+		encodings = New(r, WithEncoders(encoders)).ParseEncoding(available...)
+	}
+	if len(encodings) == 0 {
+		http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+		return
+	}
+
+	if encoding := encodings[0]; encoding != "identity" {
+		if variantFile, ok := variantFiles[encoding]; ok {
+			file, info = variantFile, variantInfos[encoding]
+			w.Header().Set("Content-Encoding", encoding)
+		}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", fileETag(info, w.Header().Get("Content-Encoding")))
+
+	http.ServeContent(w, r, name, info.ModTime(), file)
+}
+
+// open opens a regular file of the file system.
+func (p *Precompressed) open(name string) (http.File, fs.FileInfo, bool) {
+	file, err := p.Root.Open(name)
+	if err != nil {
+		return nil, nil, false
+	}
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		file.Close()
+		return nil, nil, false
+	}
+
+	return file, info, true
+}
+
+// contentType returns the media type of the original file, from its extension
+// or else from its content.
+func (p *Precompressed) contentType(name string, file http.File) (string, error) {
+	if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+		return contentType, nil
+	}
+
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// variants returns the precompressed variants to look for.
+func (p *Precompressed) variants() []FileVariant {
+	if p.Variants == nil {
+		return DefaultFileVariants
+	}
+
+	return p.Variants
+}
+
+// fileETag returns a strong ETag for a file, which differs between the
+// variants of a file.
+func fileETag(info fs.FileInfo, encoding string) string {
+	if encoding == "" {
+		return fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+	}
+
+	return fmt.Sprintf(`"%x-%x-%s"`, info.ModTime().UnixNano(), info.Size(), encoding)
+}
@@ -0,0 +1,105 @@
+package negotiator_test
+
+import (
+	"github.com/noelukwa/negotiator"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPrecompressedFileServer(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js":     {Data: []byte("console.log('plain')")},
+		"app.js.br":  {Data: []byte("brotli")},
+		"app.js.gz":  {Data: []byte("gzipped")},
+		"readme":     {Data: []byte("<html>readme</html>")},
+		"dir/a.html": {Data: []byte("<p>a</p>")},
+	}
+	handler := negotiator.PrecompressedFileServerFS(fsys)
+
+	tests := []struct {
+		name            string
+		path            string
+		acceptEncoding  string
+		status          int
+		contentEncoding string
+		contentType     string
+		body            string
+	}{
+		{"should serve the preferred variant", "/app.js", "gzip, deflate, br", http.StatusOK, "br", mime.TypeByExtension(".js"), "brotli"},
+		{"should serve the accepted variant", "/app.js", "gzip, br;q=0.5", http.StatusOK, "gzip", mime.TypeByExtension(".js"), "gzipped"},
+		{"should serve the plain file", "/app.js", "deflate", http.StatusOK, "", mime.TypeByExtension(".js"), "console.log('plain')"},
+		{"should serve the plain file without a header", "/app.js", "", http.StatusOK, "", mime.TypeByExtension(".js"), "console.log('plain')"},
+		{"should refuse everything", "/app.js", "identity;q=0", http.StatusNotAcceptable, "", "", ""},
+		{"should sniff the type of the original file", "/readme", "br", http.StatusOK, "", "text/html; charset=utf-8", "<html>readme</html>"},
+		{"should serve a missing file as http.FileServer", "/missing.js", "br", http.StatusNotFound, "", "", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			req.Header.Set("Accept-Encoding", test.acceptEncoding)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != test.status {
+				t.Fatalf("Expected status %d, got %d", test.status, rec.Code)
+			}
+			if test.status != http.StatusOK {
+				return
+			}
+
+			if contentEncoding := rec.Header().Get("Content-Encoding"); contentEncoding != test.contentEncoding {
+				t.Errorf("Expected Content-Encoding %q, got %q", test.contentEncoding, contentEncoding)
+			}
+			if contentType := rec.Header().Get("Content-Type"); contentType != test.contentType {
+				t.Errorf("Expected Content-Type %q, got %q", test.contentType, contentType)
+			}
+			if vary := rec.Header().Get("Vary"); vary != "Accept-Encoding" {
+				t.Errorf("Expected Vary Accept-Encoding, got %q", vary)
+			}
+			if rec.Body.String() != test.body {
+				t.Errorf("Expected body %q, got %q", test.body, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestPrecompressedFileServer_Conditional(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js":    {Data: []byte("console.log('plain')")},
+		"app.js.gz": {Data: []byte("gzipped")},
+	}
+	handler := negotiator.PrecompressedFileServerFS(fsys)
+
+	serve := func(acceptEncoding string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	gzipETag := serve("gzip", nil).Header().Get("ETag")
+	plainETag := serve("identity", nil).Header().Get("ETag")
+	if gzipETag == "" || gzipETag == plainETag {
+		t.Fatalf("Expected distinct ETags per variant, got %q and %q", gzipETag, plainETag)
+	}
+
+	if rec := serve("gzip", http.Header{"If-None-Match": {gzipETag}}); rec.Code != http.StatusNotModified {
+		t.Errorf("Expected status 304, got %d", rec.Code)
+	}
+	if rec := serve("identity", http.Header{"If-None-Match": {gzipETag}}); rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for another variant, got %d", rec.Code)
+	}
+
+	rec := serve("gzip", http.Header{"Range": {"bytes=0-3"}})
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "gzip" {
+		t.Errorf("Expected the first 4 bytes of the variant, got %d %q", rec.Code, rec.Body.String())
+	}
+}
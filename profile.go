@@ -0,0 +1,150 @@
+package negotiator
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Profile represents a profile URI, either from the Accept-Profile header or
+// an offer matched against it.
+// See https://www.w3.org/TR/dx-prof-conneg/
+type Profile struct {
+	URI     string
+	Quality float64
+	// Range is the profile URI from the Accept-Profile header that matched.
+	Range string
+	// Index is the position of Range in the Accept-Profile header, or -1 when
+	// the header is missing.
+	Index int
+}
+
+// Profiles returns the profile URIs listed in the profile parameter of the
+// media type, which holds a space-separated list of URIs (RFC 6906).
+func (m MediaType) Profiles() []string {
+	return strings.Fields(m.Parameters["profile"])
+}
+
+// Profile returns the offered profile URI the client prefers most, according
+// to the Accept-Profile header, or an empty string when none is acceptable.
+// The chosen URI belongs in the Content-Profile response header.
+func (n *Negotiator) Profile(offers ...string) string {
+	profiles := n.ProfileMatches(offers...)
+	if len(profiles) == 0 {
+		return ""
+	}
+
+	return profiles[0].URI
+}
+
+// Profiles returns the offered profile URIs that are acceptable to the client,
+// sorted by the client's preference.
+func (n *Negotiator) Profiles(offers ...string) []string {
+	matches := n.ProfileMatches(offers...)
+
+	result := make([]string, len(matches))
+	for i, match := range matches {
+		result[i] = match.URI
+	}
+
+	return result
+}
+
+// ProfileMatches returns the offered profile URIs that are acceptable to the
+// client according to the Accept-Profile header, sorted by priority. Offers may
+// be written with or without the enclosing angle brackets.
+func (n *Negotiator) ProfileMatches(offers ...string) []Profile {
+	acceptProfile := n.req.Header.Get("Accept-Profile")
+	if acceptProfile == "" {
+		profiles := make([]Profile, len(offers))
+		for i, offer := range offers {
+			profiles[i] = Profile{URI: offer, Quality: 1, Index: -1}
+		}
+		return profiles
+	}
+
+	parsedProfiles := splitProfiles(acceptProfile)
+
+	preferredProfiles := make([]Profile, 0, len(offers))
+	for _, offer := range offers {
+		uri := strings.Trim(strings.TrimSpace(offer), "<>")
+
+		match := Profile{URI: offer, Quality: -1}
+		for _, profile := range parsedProfiles {
+			if profile.URI != uri {
+				continue
+			}
+			if profile.Quality <= 0 {
+				match.Quality = 0
+				break
+			}
+			if profile.Quality > match.Quality {
+				match.Quality = profile.Quality
+				match.Range = profile.URI
+				match.Index = profile.Index
+			}
+		}
+
+		if match.Quality > 0 {
+			preferredProfiles = append(preferredProfiles, match)
+		}
+	}
+
+	sort.SliceStable(preferredProfiles, func(i, j int) bool {
+		if preferredProfiles[i].Quality != preferredProfiles[j].Quality {
+			return preferredProfiles[i].Quality > preferredProfiles[j].Quality
+		}
+		return preferredProfiles[i].Index < preferredProfiles[j].Index
+	})
+
+	return preferredProfiles
+}
+
+// splitProfiles splits the Accept-Profile header into individual profile URIs
+// with quality values. URIs are enclosed in angle brackets and may themselves
+// contain commas and semicolons.
+func splitProfiles(acceptProfile string) []Profile {
+	profiles := make([]Profile, 0)
+
+	for i, rawProfile := range splitUnquoted(acceptProfile, ',') {
+		parts := splitUnquoted(rawProfile, ';')
+		profile := Profile{
+			URI:     strings.Trim(strings.TrimSpace(parts[0]), "<>"),
+			Quality: 1,
+			Index:   i,
+		}
+		profile.Range = profile.URI
+
+		for _, param := range parts[1:] {
+			key, val := splitKeyValuePair(strings.TrimSpace(param))
+			if strings.EqualFold(key, "q") {
+				if q, err := strconv.ParseFloat(val, 64); err == nil {
+					profile.Quality = q
+				}
+			}
+		}
+
+		if profile.URI != "" {
+			profiles = append(profiles, profile)
+		}
+	}
+
+	return profiles
+}
+
+// profilesInclude checks if the space-separated list of profile URIs offered
+// lists every URI in the space-separated list of profile URIs wanted.
+func profilesInclude(offered string, wanted string) bool {
+	offeredSet := make(map[string]struct{})
+	for _, uri := range strings.Fields(offered) {
+		offeredSet[uri] = struct{}{}
+	}
+
+	for _, uri := range strings.Fields(wanted) {
+		if _, ok := offeredSet[uri]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
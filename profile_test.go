@@ -0,0 +1,95 @@
+package negotiator_test
+
+import (
+	"github.com/noelukwa/negotiator"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiator_Profiles(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		acceptProfile string
+		expected      []string
+		available     []string
+	}{
+		{
+			"should return offers when header is missing",
+			"",
+			[]string{"http://example.org/a", "http://example.org/b"},
+			[]string{"http://example.org/a", "http://example.org/b"},
+		},
+		{
+			"should return client-preferred profiles",
+			"<http://example.org/a>;q=0.5, <http://example.org/b>",
+			[]string{"http://example.org/b", "http://example.org/a"},
+			[]string{"http://example.org/a", "http://example.org/b"},
+		},
+		{
+			"should handle commas and semicolons inside URIs",
+			"<http://example.org/a;v=1,2>;q=0.5, <urn:example:b>;q=0.8",
+			[]string{"urn:example:b", "http://example.org/a;v=1,2"},
+			[]string{"http://example.org/a;v=1,2", "urn:example:b"},
+		},
+		{
+			"should accept offers in angle brackets",
+			"<urn:example:b>",
+			[]string{"<urn:example:b>"},
+			[]string{"<urn:example:a>", "<urn:example:b>"},
+		},
+		{
+			"should exclude refused profiles",
+			"<urn:example:a>;q=0, <urn:example:b>;q=0.1",
+			[]string{"urn:example:b"},
+			[]string{"urn:example:a", "urn:example:b"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Profile", test.acceptProfile)
+
+			neg := negotiator.New(req)
+			actual := neg.Profiles(test.available...)
+			if len(actual) != len(test.expected) {
+				t.Fatalf("Expected %s profiles, got %s", test.expected, actual)
+			}
+			for i, v := range actual {
+				if v != test.expected[i] {
+					t.Errorf("Expected %s profile, got %s", test.expected[i], v)
+				}
+			}
+
+			if best := neg.Profile(test.available...); best != test.expected[0] {
+				t.Errorf("Expected %s profile, got %s", test.expected[0], best)
+			}
+		})
+	}
+}
+
+func TestNegotiator_MediaTypeProfiles(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", `application/ld+json;profile="http://www.w3.org/ns/json-ld#compacted http://schema.org/"`)
+
+	matches := negotiator.New(req).MediaTypeMatches(
+		`application/ld+json;profile="http://www.w3.org/ns/json-ld#expanded"`,
+		`application/ld+json;profile="http://schema.org/ http://www.w3.org/ns/json-ld#compacted http://example.org/extra"`,
+	)
+	if len(matches) != 1 {
+		t.Fatalf("Expected 1 media type, got %d", len(matches))
+	}
+
+	profiles := matches[0].Profiles()
+	expected := []string{"http://schema.org/", "http://www.w3.org/ns/json-ld#compacted", "http://example.org/extra"}
+	if len(profiles) != len(expected) {
+		t.Fatalf("Expected %s profiles, got %s", expected, profiles)
+	}
+	for i, v := range profiles {
+		if v != expected[i] {
+			t.Errorf("Expected %s profile, got %s", expected[i], v)
+		}
+	}
+}
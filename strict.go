@@ -0,0 +1,250 @@
+package negotiator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseError describes a malformed Accept-* header found in strict mode.
+type ParseError struct {
+	// Header is the name of the malformed header.
+	Header string
+	// Offset is the byte offset of Token in the header value.
+	Offset int
+	// Token is the offending part of the header value.
+	Token string
+	// Reason explains why Token was rejected.
+	Reason string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("negotiator: invalid %s header at offset %d: %s: %q", e.Header, e.Offset, e.Reason, e.Token)
+}
+
+// WithStrictParsing makes the methods that report errors reject malformed
+// headers with a *ParseError instead of skipping or repairing them. These are
+// AcceptedMediaTypes for Accept, AcceptedCharsets for Accept-Charset,
+// AcceptedEncodings and Encoding for Accept-Encoding, and the methods
+// returning languages for Accept-Language. The methods without an error
+// result stay lenient; use Validate to check every header up front.
+func WithStrictParsing() Option {
+	return func(n *Negotiator) {
+		n.strict = true
+	}
+}
+
+// Validate checks the Accept, Accept-Charset, Accept-Encoding and
+// Accept-Language headers of the request against the grammar of RFC 9110
+// section 12.5 and returns a *ParseError for the first malformed header, so
+// that the request can be answered with 400 Bad Request.
+func (n *Negotiator) Validate() error {
+	for _, header := range []string{"Accept", "Accept-Charset", "Accept-Encoding", "Accept-Language"} {
+		if err := n.validateHeader(header); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkStrict validates a header in strict mode, and does nothing otherwise.
+func (n *Negotiator) checkStrict(header string) error {
+	if !n.strict {
+		return nil
+	}
+
+	return n.validateHeader(header)
+}
+
+// validateHeader checks a single Accept-* header of the request.
+func (n *Negotiator) validateHeader(header string) error {
+	value := n.req.Header.Get(header)
+
+	for _, element := range splitOffsets(value, 0, ',') {
+		if strings.TrimSpace(element.text) == "" {
+			// Empty list elements are allowed, see RFC 9110 section 5.6.1.
+			continue
+		}
+
+		if err := validateElement(header, element); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateElement checks a single element of an Accept-* header: the value,
+// followed by parameters in the Accept header only, then an optional weight
+// and, in the Accept header, accept-extensions.
+func validateElement(header string, element offsetString) error {
+	parts := splitOffsets(element.text, element.offset, ';')
+
+	value := parts[0].trim()
+	if err := validateValue(header, value); err != nil {
+		return err
+	}
+
+	weighted := false
+	for _, part := range parts[1:] {
+		param := part.trim()
+
+		key, val, ok := strings.Cut(param.text, "=")
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+		if !ok || !isToken(key) || val == "" {
+			return &ParseError{Header: header, Offset: param.offset, Token: param.text, Reason: "malformed parameter"}
+		}
+
+		if strings.EqualFold(key, "q") && !weighted {
+			if !isQValue(val) {
+				return &ParseError{Header: header, Offset: param.offset, Token: param.text, Reason: "q-value must be between 0 and 1 with at most three decimals"}
+			}
+			weighted = true
+			continue
+		}
+
+		if header != "Accept" {
+			return &ParseError{Header: header, Offset: param.offset, Token: param.text, Reason: "unexpected parameter"}
+		}
+
+		if !isToken(val) && !isQuotedString(val) {
+			return &ParseError{Header: header, Offset: param.offset, Token: param.text, Reason: "malformed parameter value"}
+		}
+	}
+
+	return nil
+}
+
+// validateValue checks the value of an Accept-* header element, before any
+// parameters.
+func validateValue(header string, value offsetString) error {
+	valid := false
+
+	switch header {
+	case "Accept":
+		typ, subtype, ok := strings.Cut(value.text, "/")
+		valid = ok && isToken(typ) && isToken(subtype) && (typ != "*" || subtype == "*")
+	case "Accept-Language":
+		valid = value.text == "*" || isLanguageRange(value.text)
+	default:
+		valid = isToken(value.text)
+	}
+
+	if !valid {
+		return &ParseError{Header: header, Offset: value.offset, Token: value.text, Reason: "malformed value"}
+	}
+
+	return nil
+}
+
+// offsetString is a part of a header value along with its byte offset.
+type offsetString struct {
+	text   string
+	offset int
+}
+
+// trim returns the part without surrounding whitespace, adjusting its offset.
+func (s offsetString) trim() offsetString {
+	trimmed := strings.TrimLeft(s.text, " \t")
+
+	return offsetString{
+		text:   strings.TrimRight(trimmed, " \t"),
+		offset: s.offset + len(s.text) - len(trimmed),
+	}
+}
+
+// splitOffsets splits a string around each separator that is not inside a
+// quoted string, keeping the offset of every part.
+func splitOffsets(str string, offset int, sep byte) []offsetString {
+	parts := make([]offsetString, 0)
+
+	start := 0
+	for _, part := range splitUnquoted(str, sep) {
+		parts = append(parts, offsetString{text: part, offset: offset + start})
+		start += len(part) + 1
+	}
+
+	return parts
+}
+
+// isToken checks if a string is an RFC 9110 token.
+func isToken(str string) bool {
+	if str == "" {
+		return false
+	}
+
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`"(),/:;<=>?@[\]{}`, c) >= 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isQuotedString checks if a string is an RFC 9110 quoted-string.
+func isQuotedString(str string) bool {
+	if len(str) < 2 || str[0] != '"' || str[len(str)-1] != '"' {
+		return false
+	}
+
+	for i := 1; i < len(str)-1; i++ {
+		switch {
+		case str[i] == '\\':
+			i++
+			if i == len(str)-1 {
+				return false
+			}
+		case str[i] == '"':
+			return false
+		}
+	}
+
+	return true
+}
+
+// isQValue checks if a string is an RFC 9110 qvalue: a number from 0 to 1 with
+// at most three decimals.
+func isQValue(str string) bool {
+	if str == "" || len(str) > 5 || (str[0] != '0' && str[0] != '1') {
+		return false
+	}
+
+	if len(str) == 1 {
+		return true
+	}
+
+	if str[1] != '.' {
+		return false
+	}
+
+	for i := 2; i < len(str); i++ {
+		if str[i] < '0' || str[i] > '9' || (str[0] == '1' && str[i] != '0') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isLanguageRange checks if a string is an RFC 4647 language range, such as
+// en or de-CH, not counting the "*" wildcard.
+func isLanguageRange(str string) bool {
+	for i, subtag := range strings.Split(str, "-") {
+		if len(subtag) == 0 || len(subtag) > 8 {
+			return false
+		}
+
+		for j := 0; j < len(subtag); j++ {
+			c := subtag[j] | 0x20
+			isAlpha := c >= 'a' && c <= 'z'
+			isDigit := subtag[j] >= '0' && subtag[j] <= '9'
+			if !isAlpha && (i == 0 || !isDigit) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
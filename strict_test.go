@@ -0,0 +1,125 @@
+package negotiator_test
+
+import (
+	"errors"
+	"github.com/noelukwa/negotiator"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiator_Validate(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		offset int
+		token  string
+	}{
+		{"should accept a valid Accept header", "Accept", `text/html;level=1;q=0.5;foo="a;b", */*;q=0.1`, -1, ""},
+		{"should accept empty list elements", "Accept", "text/html, , application/json", -1, ""},
+		{"should accept a valid Accept-Language header", "Accept-Language", "fr-CH, fr;q=0.9, *;q=0.5", -1, ""},
+		{"should accept a valid Accept-Encoding header", "Accept-Encoding", "gzip;q=1.0, identity; q=0.5, *;q=0", -1, ""},
+		{"should reject a media type without a subtype", "Accept", "text/html, text;plain", 11, "text"},
+		{"should reject a subtype without a type", "Accept", "*/html", 0, "*/html"},
+		{"should reject a q-value above 1", "Accept", "text/html;q=1.5", 10, "q=1.5"},
+		{"should reject a q-value with more than three decimals", "Accept-Charset", "utf-8;q=0.1234", 6, "q=0.1234"},
+		{"should reject a negative q-value", "Accept-Encoding", "gzip;q=-1", 5, "q=-1"},
+		{"should reject a malformed q-value", "Accept-Language", "en, de;q=abc", 7, "q=abc"},
+		{"should reject parameters outside the Accept header", "Accept-Encoding", "gzip;foo=bar;q=1", 5, "foo=bar"},
+		{"should reject a malformed language range", "Accept-Language", "en_US", 0, "en_US"},
+		{"should reject a malformed parameter", "Accept", "text/html;level", 10, "level"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(test.header, test.value)
+
+			err := negotiator.New(req).Validate()
+			if test.offset < 0 {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+
+			var parseErr *negotiator.ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Expected a ParseError, got %v", err)
+			}
+			if parseErr.Header != test.header || parseErr.Offset != test.offset || parseErr.Token != test.token {
+				t.Errorf("Expected %s at %d in %s, got %s at %d in %s", test.token, test.offset, test.header, parseErr.Token, parseErr.Offset, parseErr.Header)
+			}
+		})
+	}
+}
+
+func TestNegotiator_StrictParsing(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "en;q=2, de")
+
+	if _, err := negotiator.New(req).ParseLanguages("en", "de"); err != nil {
+		t.Errorf("Expected no error in lenient mode, got %v", err)
+	}
+
+	_, err := negotiator.New(req, negotiator.WithStrictParsing()).ParseLanguages("en", "de")
+
+	var parseErr *negotiator.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected a ParseError in strict mode, got %v", err)
+	}
+	if parseErr.Offset != 3 || parseErr.Token != "q=2" {
+		t.Errorf("Expected q=2 at 3, got %s at %d", parseErr.Token, parseErr.Offset)
+	}
+}
+
+func TestNegotiator_StrictParsing_Headers(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		call   func(n *negotiator.Negotiator) error
+		token  string
+	}{
+		{"should reject a malformed Accept header", "Accept", "text/html;q=2, */*", func(n *negotiator.Negotiator) error {
+			_, err := n.AcceptedMediaTypes(negotiator.Offers("text/html")...)
+			return err
+		}, "q=2"},
+		{"should reject a malformed Accept-Charset header", "Accept-Charset", "utf-8;q=x", func(n *negotiator.Negotiator) error {
+			_, err := n.AcceptedCharsets(negotiator.Offers("utf-8")...)
+			return err
+		}, "q=x"},
+		{"should reject a malformed Accept-Encoding header", "Accept-Encoding", "gzip;level=9", func(n *negotiator.Negotiator) error {
+			_, err := n.AcceptedEncodings(negotiator.Offers("gzip")...)
+			return err
+		}, "level=9"},
+		{"should reject a malformed Accept-Encoding header in Encoding", "Accept-Encoding", "gzip;q=1.5", func(n *negotiator.Negotiator) error {
+			_, err := n.Encoding("gzip")
+			return err
+		}, "q=1.5"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(test.header, test.value)
+
+			if err := test.call(negotiator.New(req)); err != nil {
+				t.Errorf("Expected no error in lenient mode, got %v", err)
+			}
+
+			err := test.call(negotiator.New(req, negotiator.WithStrictParsing()))
+
+			var parseErr *negotiator.ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Expected a ParseError in strict mode, got %v", err)
+			}
+			if parseErr.Header != test.header || parseErr.Token != test.token {
+				t.Errorf("Expected %s in %s, got %s in %s", test.token, test.header, parseErr.Token, parseErr.Header)
+			}
+		})
+	}
+}
@@ -0,0 +1,344 @@
+package negotiator
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	// ErrUnsupportedCharset is returned when a charset cannot be transcoded to.
+	ErrUnsupportedCharset = errors.New("negotiator: unsupported charset")
+	// ErrUnmappableCharacter is returned when a character cannot be encoded in
+	// the charset and the UnmappableError policy is used.
+	ErrUnmappableCharacter = errors.New("negotiator: character cannot be encoded in the charset")
+)
+
+// UnmappablePolicy tells a CharsetWriter what to do with characters the
+// charset cannot encode.
+type UnmappablePolicy int
+
+const (
+	// UnmappableReplace writes a question mark instead of the character.
+	UnmappableReplace UnmappablePolicy = iota
+	// UnmappableHTMLEntity writes an HTML numeric character reference, such
+	// as &#8364; for the euro sign.
+	UnmappableHTMLEntity
+	// UnmappableError stops writing and returns ErrUnmappableCharacter.
+	UnmappableError
+)
+
+// charsetEncoding appends the encoded form of a character, or reports that
+// the charset cannot encode it.
+type charsetEncoding struct {
+	encode func(dst []byte, r rune) ([]byte, bool)
+	// bom is written before the first character.
+	bom []byte
+}
+
+// charsetEncodings holds the charsets a CharsetWriter can transcode to, by
+// canonical name.
+var charsetEncodings = map[string]charsetEncoding{
+	"UTF-8":        {encode: encodeUTF8},
+	"US-ASCII":     {encode: encodeSingleByte(0x80, nil)},
+	"ISO-8859-1":   {encode: encodeSingleByte(0x100, nil)},
+	"ISO-8859-15":  {encode: encodeSingleByte(0x100, latin9Runes)},
+	"windows-1252": {encode: encodeSingleByte(0x100, windows1252Runes)},
+	"UTF-16":       {encode: encodeUTF16(false), bom: []byte{0xfe, 0xff}},
+	"UTF-16BE":     {encode: encodeUTF16(false)},
+	"UTF-16LE":     {encode: encodeUTF16(true)},
+}
+
+// latin9Runes maps the bytes where ISO-8859-15 differs from ISO-8859-1 to
+// their characters.
+var latin9Runes = map[byte]rune{
+	0xa4: '€', 0xa6: 'Š', 0xa8: 'š', 0xb4: 'Ž',
+	0xb8: 'ž', 0xbc: 'Œ', 0xbd: 'œ', 0xbe: 'Ÿ',
+}
+
+// windows1252Runes maps the bytes where windows-1252 differs from ISO-8859-1
+// to their characters. The bytes 0x81, 0x8d, 0x8f, 0x90 and 0x9d are
+// undefined.
+var windows1252Runes = map[byte]rune{
+	0x80: '€', 0x81: -1, 0x82: '‚', 0x83: 'ƒ',
+	0x84: '„', 0x85: '…', 0x86: '†', 0x87: '‡',
+	0x88: 'ˆ', 0x89: '‰', 0x8a: 'Š', 0x8b: '‹',
+	0x8c: 'Œ', 0x8d: -1, 0x8e: 'Ž', 0x8f: -1,
+	0x90: -1, 0x91: '‘', 0x92: '’', 0x93: '“',
+	0x94: '”', 0x95: '•', 0x96: '–', 0x97: '—',
+	0x98: '˜', 0x99: '™', 0x9a: 'š', 0x9b: '›',
+	0x9c: 'œ', 0x9d: -1, 0x9e: 'ž', 0x9f: 'Ÿ',
+}
+
+// encodeSingleByte returns the encoder of a charset that maps the characters
+// below limit to the byte of the same value, except for the bytes in
+// overrides, which map to other characters.
+func encodeSingleByte(limit rune, overrides map[byte]rune) func(dst []byte, r rune) ([]byte, bool) {
+	reverse := make(map[rune]byte, len(overrides))
+	for b, r := range overrides {
+		if r >= 0 {
+			reverse[r] = b
+		}
+	}
+
+	return func(dst []byte, r rune) ([]byte, bool) {
+		if b, ok := reverse[r]; ok {
+			return append(dst, b), true
+		}
+		if r < 0 || r >= limit {
+			return dst, false
+		}
+		if _, ok := overrides[byte(r)]; ok {
+			return dst, false
+		}
+
+		return append(dst, byte(r)), true
+	}
+}
+
+// encodeUTF8 encodes a character in UTF-8, which can encode every character.
+func encodeUTF8(dst []byte, r rune) ([]byte, bool) {
+	return utf8.AppendRune(dst, r), true
+}
+
+// encodeUTF16 returns the encoder of big-endian or little-endian UTF-16.
+func encodeUTF16(littleEndian bool) func(dst []byte, r rune) ([]byte, bool) {
+	return func(dst []byte, r rune) ([]byte, bool) {
+		units := []rune{r}
+		if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError || r2 != utf8.RuneError {
+			units = []rune{r1, r2}
+		}
+
+		for _, unit := range units {
+			if littleEndian {
+				dst = append(dst, byte(unit), byte(unit>>8))
+			} else {
+				dst = append(dst, byte(unit>>8), byte(unit))
+			}
+		}
+
+		return dst, true
+	}
+}
+
+// CharsetWriter is an http.ResponseWriter that transcodes a UTF-8 text
+// response into another charset as it is written. Responses are transcoded
+// when their media type is text/* or XML, and their Content-Type names the
+// UTF-8 charset or no charset, and the charset parameter of the Content-Type
+// is set to the new charset. Other responses, such as JSON, which is always
+// UTF-8, are written unchanged.
+//
+// Characters split across writes are held back until they are complete, so
+// Close must be called once the response is written.
+type CharsetWriter struct {
+	w           http.ResponseWriter
+	charset     string
+	encoding    charsetEncoding
+	policy      UnmappablePolicy
+	wroteHeader bool
+	transcode   bool
+	wroteBOM    bool
+	pending     []byte
+	err         error
+}
+
+// NewCharsetWriter returns a CharsetWriter that transcodes to the charset,
+// which is one of US-ASCII, ISO-8859-1, ISO-8859-15, windows-1252, UTF-8,
+// UTF-16, UTF-16BE or UTF-16LE, or an alias of them. Other charsets return
+// ErrUnsupportedCharset.
+func NewCharsetWriter(w http.ResponseWriter, charset string, policy UnmappablePolicy) (*CharsetWriter, error) {
+	name := CanonicalCharset(charset)
+	encoding, ok := charsetEncodings[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCharset, charset)
+	}
+
+	return &CharsetWriter{w: w, charset: name, encoding: encoding, policy: policy}, nil
+}
+
+// Header returns the header map of the underlying ResponseWriter.
+func (cw *CharsetWriter) Header() http.Header {
+	return cw.w.Header()
+}
+
+// WriteHeader sets the charset parameter of the Content-Type and writes the
+// status code.
+func (cw *CharsetWriter) WriteHeader(statusCode int) {
+	cw.writeHeader(statusCode, nil)
+}
+
+// transcodedMediaRanges lists the media ranges of the responses a
+// CharsetWriter transcodes. JSON is left out, as RFC 8259 requires it to be
+// UTF-8 and it has no charset parameter.
+var transcodedMediaRanges = []string{"text/*", "application/xml", "application/*+xml"}
+
+// isTranscodable checks if responses of a media type may be transcoded.
+func isTranscodable(mediaType string) bool {
+	parsed := parseMediaType(mediaType)
+	if parsed == nil {
+		return false
+	}
+
+	return mediaRangesMatch(transcodedMediaRanges, *parsed) >= 0
+}
+
+// writeHeader decides whether to transcode the response, detecting its
+// Content-Type from the first write when it is not set.
+func (cw *CharsetWriter) writeHeader(statusCode int, p []byte) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+
+	header := cw.w.Header()
+	contentType := header.Get("Content-Type")
+	if contentType == "" && p != nil {
+		contentType = http.DetectContentType(p)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		charset, ok := params["charset"]
+		if (!ok || charsetKey(charset) == "utf-8") && isTranscodable(mediaType) {
+			cw.transcode = true
+			params["charset"] = cw.charset
+			header.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+			header.Del("Content-Length")
+		}
+	}
+
+	cw.w.WriteHeader(statusCode)
+}
+
+// Write transcodes p and writes it to the underlying ResponseWriter. It
+// returns the number of bytes of p consumed. Under the UnmappableError policy
+// an unmappable character stops the response, and every later write returns
+// the same error.
+func (cw *CharsetWriter) Write(p []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.writeHeader(http.StatusOK, p)
+	}
+	if !cw.transcode {
+		return cw.w.Write(p)
+	}
+	if cw.err != nil {
+		return 0, cw.err
+	}
+
+	held := len(cw.pending)
+	data := append(cw.pending, p...)
+	cw.pending = nil
+
+	buf := make([]byte, 0, len(data))
+	if !cw.wroteBOM {
+		buf = append(buf, cw.encoding.bom...)
+		cw.wroteBOM = true
+	}
+
+	consumed := 0
+	for consumed < len(data) && utf8.FullRune(data[consumed:]) {
+		r, size := utf8.DecodeRune(data[consumed:])
+		if buf, cw.err = cw.appendRune(buf, r); cw.err != nil {
+			break
+		}
+		consumed += size
+	}
+
+	if cw.err == nil {
+		cw.pending = append([]byte(nil), data[consumed:]...)
+		consumed = len(data)
+	}
+
+	if _, err := cw.w.Write(buf); err != nil && cw.err == nil {
+		cw.err = err
+	}
+
+	n := consumed - held
+	if n < 0 {
+		n = 0
+	}
+
+	return n, cw.err
+}
+
+// appendRune appends the encoded character, applying the unmappable policy.
+func (cw *CharsetWriter) appendRune(buf []byte, r rune) ([]byte, error) {
+	buf, ok := cw.encoding.encode(buf, r)
+	if ok {
+		return buf, nil
+	}
+
+	switch cw.policy {
+	case UnmappableHTMLEntity:
+		buf = append(buf, "&#"...)
+		buf = strconv.AppendInt(buf, int64(r), 10)
+		return append(buf, ';'), nil
+	case UnmappableError:
+		return buf, fmt.Errorf("%w: %U in %s", ErrUnmappableCharacter, r, cw.charset)
+	default:
+		return append(buf, '?'), nil
+	}
+}
+
+// Flush writes any buffered data of the underlying ResponseWriter to the
+// client. A character split across writes stays held back.
+func (cw *CharsetWriter) Flush() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := cw.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close writes a character left incomplete by the last write as an invalid
+// character, and returns the error that stopped the response, if any.
+func (cw *CharsetWriter) Close() error {
+	if cw.err != nil || len(cw.pending) == 0 {
+		return cw.err
+	}
+
+	buf, err := cw.appendRune(nil, utf8.RuneError)
+	cw.pending = nil
+	if err != nil {
+		cw.err = err
+		return err
+	}
+
+	_, err = cw.w.Write(buf)
+	return err
+}
+
+// TranscodeCharset returns middleware that negotiates the response charset
+// from the available charsets with ParseCharsets, and transcodes the UTF-8
+// text responses of the handler into it with a CharsetWriter. Responses are
+// left alone when the client prefers UTF-8 or a charset that cannot be
+// transcoded to. The error returned by closing the CharsetWriter, such as
+// ErrUnmappableCharacter, is lost, since the response has been written by
+// then; handlers that must know use a CharsetWriter of their own.
+func TranscodeCharset(policy UnmappablePolicy, available ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Charset")
+
+			charsets := New(r).ParseCharsets(available...)
+			if len(charsets) == 0 || charsetKey(charsets[0]) == "utf-8" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw, err := NewCharsetWriter(w, charsets[0], policy)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(cw, r)
+			cw.Close()
+		})
+	}
+}
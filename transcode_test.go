@@ -0,0 +1,148 @@
+package negotiator_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/noelukwa/negotiator"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCharsetWriter(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		charset     string
+		policy      negotiator.UnmappablePolicy
+		body        string
+		expected    []byte
+		contentType string
+	}{
+		{"should encode ISO-8859-1", "latin1", negotiator.UnmappableReplace, "café €", []byte("caf\xe9 ?"), "text/plain; charset=ISO-8859-1"},
+		{"should encode windows-1252", "cp1252", negotiator.UnmappableReplace, "café €", []byte("caf\xe9 \x80"), "text/plain; charset=windows-1252"},
+		{"should encode ISO-8859-15", "latin-9", negotiator.UnmappableReplace, "€ ¤", []byte("\xa4 ?"), "text/plain; charset=ISO-8859-15"},
+		{"should encode US-ASCII", "ascii", negotiator.UnmappableHTMLEntity, "naïve", []byte("na&#239;ve"), "text/plain; charset=US-ASCII"},
+		{"should encode UTF-16 with a byte order mark", "utf-16", negotiator.UnmappableReplace, "a😀", []byte{0xfe, 0xff, 0x00, 'a', 0xd8, 0x3d, 0xde, 0x00}, "text/plain; charset=UTF-16"},
+		{"should encode UTF-16LE", "utf-16le", negotiator.UnmappableReplace, "aé", []byte{'a', 0x00, 0xe9, 0x00}, "text/plain; charset=UTF-16LE"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			rec.Header().Set("Content-Type", "text/plain")
+
+			cw, err := negotiator.NewCharsetWriter(rec, test.charset, test.policy)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			// Write one byte at a time, splitting multi-byte characters.
+			body := []byte(test.body)
+			for i := range body {
+				if _, err := cw.Write(body[i : i+1]); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+			if err := cw.Close(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !bytes.Equal(rec.Body.Bytes(), test.expected) {
+				t.Errorf("Expected body %q, got %q", test.expected, rec.Body.Bytes())
+			}
+			if contentType := rec.Header().Get("Content-Type"); contentType != test.contentType {
+				t.Errorf("Expected Content-Type %s, got %s", test.contentType, contentType)
+			}
+		})
+	}
+}
+
+func TestCharsetWriter_UnmappableError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	cw, _ := negotiator.NewCharsetWriter(rec, "ISO-8859-1", negotiator.UnmappableError)
+
+	n, err := cw.Write([]byte("ok € no"))
+	if !errors.Is(err, negotiator.ErrUnmappableCharacter) {
+		t.Fatalf("Expected ErrUnmappableCharacter, got %v", err)
+	}
+	if n != 3 || rec.Body.String() != "ok " {
+		t.Errorf("Expected 3 bytes written as \"ok \", got %d as %q", n, rec.Body.String())
+	}
+	if _, err := cw.Write([]byte("more")); !errors.Is(err, negotiator.ErrUnmappableCharacter) {
+		t.Errorf("Expected later writes to fail, got %v", err)
+	}
+}
+
+func TestCharsetWriter_Passthrough(t *testing.T) {
+	for _, contentType := range []string{"application/json", "application/json; charset=utf-8", "application/ld+json; charset=utf-8", "text/plain; charset=koi8-r"} {
+		t.Run(contentType, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			rec.Header().Set("Content-Type", contentType)
+
+			cw, _ := negotiator.NewCharsetWriter(rec, "ISO-8859-1", negotiator.UnmappableReplace)
+			io.WriteString(cw, `{"name":"café"}`)
+			cw.Close()
+
+			if rec.Body.String() != `{"name":"café"}` || rec.Header().Get("Content-Type") != contentType {
+				t.Errorf("Expected the response unchanged, got %q as %s", rec.Body.String(), rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestCharsetWriter_XML(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+
+	cw, _ := negotiator.NewCharsetWriter(rec, "ISO-8859-1", negotiator.UnmappableReplace)
+	io.WriteString(cw, "<title>café</title>")
+	cw.Close()
+
+	if rec.Body.String() != "<title>caf\xe9</title>" || rec.Header().Get("Content-Type") != "application/atom+xml; charset=ISO-8859-1" {
+		t.Errorf("Expected the response transcoded, got %q as %s", rec.Body.String(), rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestNewCharsetWriter_Unsupported(t *testing.T) {
+	if _, err := negotiator.NewCharsetWriter(httptest.NewRecorder(), "Shift_JIS", negotiator.UnmappableReplace); !errors.Is(err, negotiator.ErrUnsupportedCharset) {
+		t.Errorf("Expected ErrUnsupportedCharset, got %v", err)
+	}
+}
+
+func TestTranscodeCharset(t *testing.T) {
+	handler := negotiator.TranscodeCharset(negotiator.UnmappableHTMLEntity, "UTF-8", "ISO-8859-1")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "<p>café €</p>")
+	}))
+
+	tests := []struct {
+		name          string
+		acceptCharset string
+		expected      string
+		contentType   string
+	}{
+		{"should transcode to the preferred charset", "iso-8859-1, utf-8;q=0.5", "<p>caf\xe9 &#8364;</p>", "text/html; charset=ISO-8859-1"},
+		{"should keep UTF-8", "utf-8, iso-8859-1;q=0.5", "<p>café €</p>", "text/html; charset=utf-8"},
+		{"should keep UTF-8 without a header", "", "<p>café €</p>", "text/html; charset=utf-8"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Charset", test.acceptCharset)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Body.String() != test.expected {
+				t.Errorf("Expected body %q, got %q", test.expected, rec.Body.String())
+			}
+			if contentType := rec.Header().Get("Content-Type"); contentType != test.contentType {
+				t.Errorf("Expected Content-Type %s, got %s", test.contentType, contentType)
+			}
+		})
+	}
+}
@@ -0,0 +1,341 @@
+package negotiator
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrVersionNotSupported is returned when the client asks for API versions
+// that the server does not support.
+var ErrVersionNotSupported = errors.New("negotiator: no supported version matches the request")
+
+// Version is a semantic version such as 3, 2.1 or 1.4.2. Missing minor and
+// patch numbers are zero.
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// ParseVersion parses a version such as 3, v2.1 or 1.4.2.
+func ParseVersion(str string) (Version, error) {
+	partial, err := parsePartialVersion(str)
+	if err != nil {
+		return Version{}, err
+	}
+
+	return partial.Version, nil
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Compare returns -1, 0 or 1 depending on whether v is lower than, equal to or
+// higher than other.
+func (v Version) Compare(other Version) int {
+	for _, diff := range []int{v.Major - other.Major, v.Minor - other.Minor, v.Patch - other.Patch} {
+		if diff < 0 {
+			return -1
+		}
+		if diff > 0 {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// VersionPolicy describes how the API versions a client asks for in the Accept
+// header are matched against the versions the server supports.
+type VersionPolicy struct {
+	// Vendor is the vendor prefix of versioned subtypes, such as vnd.acme for
+	// application/vnd.acme.v3+json. When empty, any vendor is accepted.
+	Vendor string
+	// Parameter is the media type parameter holding the version, such as
+	// version in application/json;version=2. It defaults to "version".
+	Parameter string
+	// Supported lists the versions the server supports.
+	Supported []string
+	// Default is the version used when the client asks for none. When empty,
+	// the highest supported version is used.
+	Default string
+}
+
+// APIVersion is the result of version negotiation.
+type APIVersion struct {
+	// Version is the chosen entry of VersionPolicy.Supported.
+	Version string
+	// Range is the client media range the version was taken from, or an empty
+	// string when the default version was used.
+	Range string
+	// Default reports whether the client asked for no version.
+	Default bool
+}
+
+// NegotiateVersion picks the API version to serve. The version is taken from a
+// vendor subtype such as application/vnd.acme.v3+json or from a version
+// parameter such as application/json;version=">=2 <4". A version parameter
+// holds a version or a range of versions: comparators such as >=2 or ^1.2 are
+// separated by spaces, which must all match, or by ||, of which one must
+// match. A bare version such as 3 matches every 3.x.y version.
+//
+// Media ranges are tried in order of preference, and the highest supported
+// version within the first satisfiable range is chosen. Media ranges without a
+// version select the default version, but only once every versioned range has
+// been tried. When the client only asks for versions the server does not
+// support, ErrVersionNotSupported is returned.
+func (n *Negotiator) NegotiateVersion(policy VersionPolicy) (APIVersion, error) {
+	accept := n.req.Header.Get("Accept")
+	if accept == "" {
+		return policy.defaultVersion()
+	}
+
+	mediaRanges := splitMediaTypes(accept)
+	sortMediaTypesByPriority(mediaRanges)
+
+	unversioned := false
+	for _, mediaRange := range mediaRanges {
+		if mediaRange.Quality <= 0 {
+			continue
+		}
+
+		constraint, ok := policy.versionConstraint(mediaRange)
+		if !ok {
+			unversioned = true
+			continue
+		}
+
+		ranges, err := parseVersionRanges(constraint)
+		if err != nil {
+			continue
+		}
+
+		if version, ok := policy.highestSupported(ranges); ok {
+			return APIVersion{Version: version, Range: mediaRange.Value}, nil
+		}
+	}
+
+	if unversioned {
+		return policy.defaultVersion()
+	}
+
+	return APIVersion{}, ErrVersionNotSupported
+}
+
+// defaultVersion returns the version used when the client asks for none.
+func (p VersionPolicy) defaultVersion() (APIVersion, error) {
+	if p.Default != "" {
+		return APIVersion{Version: p.Default, Default: true}, nil
+	}
+
+	version, ok := p.highestSupported(nil)
+	if !ok {
+		return APIVersion{}, ErrVersionNotSupported
+	}
+
+	return APIVersion{Version: version, Default: true}, nil
+}
+
+// versionConstraint extracts the version a media range asks for, first from
+// the version parameter, then from a versioned vendor subtype.
+func (p VersionPolicy) versionConstraint(mediaRange MediaType) (string, bool) {
+	parameter := p.Parameter
+	if parameter == "" {
+		parameter = "version"
+	}
+
+	if constraint, ok := mediaRange.Parameters[strings.ToLower(parameter)]; ok {
+		return constraint, true
+	}
+
+	subtype := strings.ToLower(mediaRange.Subtype)
+	if i := strings.LastIndex(subtype, "+"); i >= 0 {
+		subtype = subtype[:i]
+	}
+
+	i := strings.LastIndex(subtype, ".v")
+	if i < 0 || !strings.HasPrefix(subtype, "vnd.") {
+		return "", false
+	}
+
+	if p.Vendor != "" && subtype[:i] != strings.ToLower(p.Vendor) {
+		return "", false
+	}
+
+	if _, err := parsePartialVersion(subtype[i+2:]); err != nil {
+		return "", false
+	}
+
+	return subtype[i+2:], true
+}
+
+// highestSupported returns the highest supported version that satisfies any
+// of the version ranges. Without version ranges, every version satisfies.
+func (p VersionPolicy) highestSupported(ranges [][]versionComparator) (string, bool) {
+	best, bestVersion := "", Version{}
+	for _, supported := range p.Supported {
+		version, err := ParseVersion(supported)
+		if err != nil {
+			continue
+		}
+
+		if ranges != nil && !satisfiesVersionRanges(version, ranges) {
+			continue
+		}
+
+		if best == "" || version.Compare(bestVersion) > 0 {
+			best, bestVersion = supported, version
+		}
+	}
+
+	return best, best != ""
+}
+
+// partialVersion is a version in which minor and patch numbers may be left out.
+type partialVersion struct {
+	Version
+	parts int
+}
+
+// parsePartialVersion parses a version with an optional leading v, such as 2
+// or v1.4.
+func parsePartialVersion(str string) (partialVersion, error) {
+	str = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(str), "v"), "V")
+
+	parts := strings.Split(str, ".")
+	if len(parts) > 3 {
+		return partialVersion{}, fmt.Errorf("negotiator: invalid version %q", str)
+	}
+
+	numbers := [3]int{}
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 || strings.HasPrefix(part, "+") {
+			return partialVersion{}, fmt.Errorf("negotiator: invalid version %q", str)
+		}
+		numbers[i] = number
+	}
+
+	return partialVersion{
+		Version: Version{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]},
+		parts:   len(parts),
+	}, nil
+}
+
+// next returns the lowest version that does not start with the partial
+// version, such as 3.0.0 for 2 or 2.2.0 for 2.1.
+func (p partialVersion) next() Version {
+	switch p.parts {
+	case 1:
+		return Version{Major: p.Major + 1}
+	case 2:
+		return Version{Major: p.Major, Minor: p.Minor + 1}
+	default:
+		return Version{Major: p.Major, Minor: p.Minor, Patch: p.Patch + 1}
+	}
+}
+
+// versionComparator is a single comparison in a version range, such as >=2.
+type versionComparator struct {
+	operator string
+	version  partialVersion
+}
+
+// matches checks if a version satisfies the comparator.
+func (c versionComparator) matches(v Version) bool {
+	lower, upper := c.version.Version, c.version.next()
+
+	switch c.operator {
+	case ">=":
+		return v.Compare(lower) >= 0
+	case ">":
+		return v.Compare(upper) >= 0
+	case "<=":
+		return v.Compare(upper) < 0
+	case "<":
+		return v.Compare(lower) < 0
+	case "!=":
+		return v.Compare(lower) < 0 || v.Compare(upper) >= 0
+	case "^":
+		if lower.Major > 0 || c.version.parts == 1 {
+			upper = Version{Major: lower.Major + 1}
+		} else {
+			upper = Version{Minor: lower.Minor + 1}
+		}
+		return v.Compare(lower) >= 0 && v.Compare(upper) < 0
+	case "~":
+		if c.version.parts > 1 {
+			upper = Version{Major: lower.Major, Minor: lower.Minor + 1}
+		}
+		return v.Compare(lower) >= 0 && v.Compare(upper) < 0
+	default:
+		return v.Compare(lower) >= 0 && v.Compare(upper) < 0
+	}
+}
+
+// parseVersionRanges parses a version range such as ">=2 <4 || 6", returning
+// the alternatives, each made of comparators that must all match.
+func parseVersionRanges(str string) ([][]versionComparator, error) {
+	ranges := make([][]versionComparator, 0)
+
+	for _, alternative := range strings.Split(str, "||") {
+		comparators := make([]versionComparator, 0)
+
+		fields := strings.Fields(alternative)
+		for i := 0; i < len(fields); i++ {
+			field := fields[i]
+
+			operator := ""
+			for _, op := range []string{">=", "<=", "!=", ">", "<", "=", "^", "~"} {
+				if strings.HasPrefix(field, op) {
+					operator, field = op, field[len(op):]
+					break
+				}
+			}
+
+			// Allow a space between the operator and the version.
+			if field == "" && i+1 < len(fields) {
+				i++
+				field = fields[i]
+			}
+
+			version, err := parsePartialVersion(field)
+			if err != nil {
+				return nil, err
+			}
+
+			comparators = append(comparators, versionComparator{operator: operator, version: version})
+		}
+
+		if len(comparators) == 0 {
+			return nil, fmt.Errorf("negotiator: invalid version range %q", str)
+		}
+
+		ranges = append(ranges, comparators)
+	}
+
+	return ranges, nil
+}
+
+// satisfiesVersionRanges checks if a version satisfies every comparator of at
+// least one of the alternatives.
+func satisfiesVersionRanges(v Version, ranges [][]versionComparator) bool {
+	for _, comparators := range ranges {
+		satisfied := true
+		for _, comparator := range comparators {
+			if !comparator.matches(v) {
+				satisfied = false
+				break
+			}
+		}
+
+		if satisfied {
+			return true
+		}
+	}
+
+	return false
+}
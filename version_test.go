@@ -0,0 +1,71 @@
+package negotiator_test
+
+import (
+	"errors"
+	"github.com/noelukwa/negotiator"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiator_NegotiateVersion(t *testing.T) {
+
+	policy := negotiator.VersionPolicy{
+		Vendor:    "vnd.acme",
+		Supported: []string{"1", "2", "2.1", "3", "4.0.2"},
+		Default:   "2",
+	}
+
+	tests := []struct {
+		name     string
+		accept   string
+		expected string
+		isDef    bool
+		err      error
+	}{
+		{"should use the default version without a header", "", "2", true, nil},
+		{"should use the default version without a version", "application/json", "2", true, nil},
+		{"should take the version from a vendor subtype", "application/vnd.acme.v3+json", "3", false, nil},
+		{"should choose the highest minor version of a major version", "application/vnd.acme.v2+json", "2.1", false, nil},
+		{"should take the version from a parameter", "application/json; version=1", "1", false, nil},
+		{"should choose the highest version within a range", `application/json; version=">=2 <4"`, "3", false, nil},
+		{"should support alternatives", `application/json; version="1 || >=4"`, "4.0.2", false, nil},
+		{"should support caret ranges", `application/json; version="^2.0"`, "2.1", false, nil},
+		{"should support tilde ranges", `application/json; version="~2.0"`, "2", false, nil},
+		{"should support exclusions", `application/json; version=">=2 != 3 <4.1"`, "4.0.2", false, nil},
+		{"should ignore other vendors", "application/vnd.other.v3+json", "2", true, nil},
+		{"should try ranges in order of preference", "application/vnd.acme.v9+json, application/vnd.acme.v1+json;q=0.5", "1", false, nil},
+		{"should fall back to the default for a later wildcard", "application/vnd.acme.v9+json, */*;q=0.1", "2", true, nil},
+		{"should prefer a later versioned range", "application/json, application/vnd.acme.v3+json", "3", false, nil},
+		{"should prefer a versioned range of lower quality", "*/*, application/vnd.acme.v1+json;q=0.5", "1", false, nil},
+		{"should fail when no version is supported", "application/vnd.acme.v9+json", "", false, negotiator.ErrVersionNotSupported},
+		{"should fail when no version is in range", `application/json; version=">4.0.2"`, "", false, negotiator.ErrVersionNotSupported},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept", test.accept)
+
+			version, err := negotiator.New(req).NegotiateVersion(policy)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("Expected %v error, got %v", test.err, err)
+			}
+			if version.Version != test.expected || version.Default != test.isDef {
+				t.Errorf("Expected %s version (default %v), got %s (default %v)", test.expected, test.isDef, version.Version, version.Default)
+			}
+		})
+	}
+}
+
+func TestNegotiator_NegotiateVersionWithoutDefault(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	version, err := negotiator.New(req).NegotiateVersion(negotiator.VersionPolicy{Supported: []string{"v1", "v3", "v2"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if version.Version != "v3" {
+		t.Errorf("Expected v3 version, got %s", version.Version)
+	}
+}